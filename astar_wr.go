@@ -18,7 +18,14 @@ type Astar struct {
 
 	MaxIndex int
 
-	CostMap [][]byte //for each object, object COST = 0xff
+	CostMap    [][]byte      //for each object, object COST = 0xff
+	Directions *DirectionMap // one-way lanes (nil: no traffic rule)
+	Unknown    [][]bool      // unknown cells of SLAM map (nil: all known)
+	Iteration  int           // object inflation of CostMap (for local re-inflation)
+
+	Version   uint64          // incremented on every map change (see NotifyChanged)
+	Layers    *LayeredCostMap // layers for live update (made on first update if nil)
+	listeners []MapListener
 
 	OpenSet   map[int]*AstarNode
 	CloseSet  map[int]*AstarNode
@@ -74,18 +81,23 @@ func heuristic(n1, n2 *AstarNode, weight float64) float64 {
 }
 
 func (a Astar) verifyGrid(index int) bool {
-	if index > a.MaxIndex {
+	if index < 0 || index > a.MaxIndex {
 		return false
 	}
 	px, py := a.indToPosXY(index)
-	//	fmt.Printf("verify %d %d : %d\n", px, py, a.CostMap[px][py])
-	if px >= a.MaxX {
+	return a.verifyPoint(px, py)
+}
+
+// verifyPoint returns false if (x,y) is out of map or object
+func (a Astar) verifyPoint(x, y int) bool {
+	//	fmt.Printf("verify %d %d : %d\n", x, y, a.CostMap[x][y])
+	if x < 0 || x > a.MaxX {
 		return false
-	} else if py >= a.MaxY {
+	} else if y < 0 || y > a.MaxY {
 		return false
 	}
 
-	if a.CostMap[px][py] == 0xff {
+	if a.CostMap[x][y] == 0xff {
 		return false
 	}
 	return true
@@ -97,15 +109,16 @@ func (a Astar) nodeToInd(n *AstarNode) int {
 }
 
 // Astar planing (sx,sy) is start, (gx,gy) is goal point
+// if the route passes unknown cells, the route is returned with *UnknownSpaceError
 func (a *Astar) Plan(sx, sy, gx, gy int, weight float64) (route [][2]int, err error) {
 	nstart := newNode(sx, sy, 0.0, -1)
 	ngoal := newNode(gx, gy, 0.0, -1)
 
-	if !a.verifyGrid(a.nodeToInd(nstart)) {
+	if !a.verifyPoint(sx, sy) {
 		err = fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
 		return route, err
 	}
-	if !a.verifyGrid(a.nodeToInd(ngoal)) {
+	if !a.verifyPoint(gx, gy) {
 		err = fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
 		return route, err
 	}
@@ -149,7 +162,7 @@ func (a *Astar) Plan(sx, sy, gx, gy int, weight float64) (route [][2]int, err er
 			ngoal.PrevIndex = current.PrevIndex
 			ngoal.Cost = current.Cost
 			route = a.finalPath(ngoal, close_set)
-			return route, nil
+			return route, a.checkUnknown(route) // route is returned even if it passes unknown cells
 		}
 
 		delete(open_set, cId)
@@ -162,7 +175,7 @@ func (a *Astar) Plan(sx, sy, gx, gy int, weight float64) (route [][2]int, err er
 
 		var nId int
 		var node *AstarNode
-		for _, v := range motion {
+		for k, v := range motion {
 			nx := current.Ix + int(v[0])
 			if nx < 0 || nx > a.MaxX {
 				continue
@@ -171,7 +184,8 @@ func (a *Astar) Plan(sx, sy, gx, gy int, weight float64) (route [][2]int, err er
 			if ny < 0 || ny > a.MaxY {
 				continue
 			}
-			if a.CostMap[nx][ny] == 0xff {
+			cost, ok := a.moveCost(current.Ix, current.Iy, k)
+			if !ok {
 				continue
 			}
 			// in the closed set?
@@ -182,7 +196,7 @@ func (a *Astar) Plan(sx, sy, gx, gy int, weight float64) (route [][2]int, err er
 			if _, ok := open_set[nId]; ok {
 				continue
 			}
-			node = newNode(nx, ny, current.Cost+v[2]+float64(a.CostMap[nx][ny]), cId)
+			node = newNode(nx, ny, current.Cost+cost, cId)
 			if a.UpdateObj != nil {
 				a.Current = node
 				a.UpdateObj.UpdateAstar(a, color.RGBA{0x00, 0xb0, 0x0b0, 0xff}, 0)
@@ -0,0 +1,111 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bidirectional A* with balanced potentials (Ikeda et al. 1994)
+// pf(v) = (|v-goal| - |v-start|)/2 for forward search, -pf(v) for backward search.
+// the search stops when sum of top keys exceeds the best meeting cost, result is optimal for weight <= 1.
+
+// PlanBidirectional plans route from (sx,sy) to (gx,gy) searching from both ends (Plan format).
+// route cost is the optimal cost (same as Dijkstra) also on directed (one-way) maps.
+func (a *Astar) PlanBidirectional(sx, sy, gx, gy int, weight float64) ([][2]int, error) {
+	if !a.verifyPoint(sx, sy) {
+		return nil, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	pf := func(x, y int) float64 {
+		return weight * (math.Hypot(float64(x-gx), float64(y-gy)) - math.Hypot(float64(x-sx), float64(y-sy))) / 2
+	}
+	n := a.Width * a.Height
+	var dist [2][]float64 // 0: forward from start, 1: backward from goal
+	var link [2][]int32   // forward: previous cell, backward: next cell
+	var closed [2][]bool
+	for d := 0; d < 2; d++ {
+		dist[d] = make([]float64, n)
+		link[d] = make([]int32, n)
+		closed[d] = make([]bool, n)
+		for i := range dist[d] {
+			dist[d][i] = math.Inf(1)
+			link[d][i] = -1
+		}
+	}
+	s, g := sy*a.Width+sx, gy*a.Width+gx
+	dist[0][s], dist[1][g] = 0, 0
+	pq := [2]*priorityQueue{{}, {}}
+	pq[0].push(s, pf(sx, sy))
+	pq[1].push(g, -pf(gx, gy))
+	best, meet := math.Inf(1), -1
+	if s == g {
+		best, meet = 0, s
+	}
+	for pq[0].Len() > 0 && pq[1].Len() > 0 {
+		if (*pq[0])[0].priority+(*pq[1])[0].priority >= best {
+			break
+		}
+		d := 0 // expand smaller queue
+		if pq[1].Len() < pq[0].Len() {
+			d = 1
+		}
+		c := pq[d].pop().key
+		if closed[d][c] {
+			continue
+		}
+		closed[d][c] = true
+		cx, cy := a.indToPosXY(c)
+		for k, v := range motion {
+			var nx, ny int
+			var mc float64
+			var ok bool
+			if d == 0 { // c -> n
+				nx, ny = cx+int(v[0]), cy+int(v[1])
+				if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+					continue
+				}
+				mc, ok = a.moveCost(cx, cy, k)
+			} else { // n -> c
+				nx, ny = cx-int(v[0]), cy-int(v[1])
+				if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+					continue
+				}
+				mc, ok = a.moveCost(nx, ny, k)
+			}
+			if !ok {
+				continue
+			}
+			nid := ny*a.Width + nx
+			nd := dist[d][c] + mc
+			if nd < dist[d][nid] {
+				dist[d][nid] = nd
+				link[d][nid] = int32(c)
+				p := pf(nx, ny)
+				if d == 1 {
+					p = -p
+				}
+				pq[d].push(nid, nd+p)
+			}
+			if total := dist[d][nid] + dist[1-d][nid]; total < best {
+				best, meet = total, nid
+			}
+		}
+	}
+	if meet < 0 {
+		return nil, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): goal is not reachable", sx, sy, gx, gy)
+	}
+	// goal ... meet
+	var route [][2]int
+	for i := int32(meet); i != -1; i = link[1][i] {
+		x, y := a.indToPosXY(int(i))
+		route = append([][2]int{{x, y}}, route...)
+	}
+	// meet ... start
+	for i := link[0][meet]; i != -1; i = link[0][i] {
+		x, y := a.indToPosXY(int(i))
+		route = append(route, [2]int{x, y})
+	}
+	return route, a.checkUnknown(route)
+}
@@ -0,0 +1,115 @@
+package astar_wr
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomMap is w x h map with objects at probability p, same seed gives same map
+func randomMap(w, h int, p float64, seed int64) *Astar {
+	rnd := rand.New(rand.NewSource(seed))
+	var objects [][2]int
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if rnd.Float64() < p {
+				objects = append(objects, [2]int{x, y})
+			}
+		}
+	}
+	return NewWeightedAstar(objects, w, h, 0)
+}
+
+// randomOneWay sets one-way rules (forbidden or penalized) to random cells
+func randomOneWay(a *Astar, p float64, seed int64) {
+	rnd := rand.New(rand.NewSource(seed))
+	a.Directions = NewDirectionMap(a)
+	for x := 0; x < a.Width; x++ {
+		for y := 0; y < a.Height; y++ {
+			if rnd.Float64() < p {
+				a.Directions.SetOneWay(x, y, float64(rnd.Intn(4))*math.Pi/2, float64(rnd.Intn(3)*5))
+			}
+		}
+	}
+}
+
+func TestPlanBidirectionalOptimal(t *testing.T) {
+	cases := []struct {
+		name   string
+		p      float64
+		oneWay float64
+	}{
+		{"open", 0.1, 0},
+		{"dense", 0.3, 0},
+		{"oneway", 0.1, 0.3},
+		{"dense oneway", 0.25, 0.5},
+	}
+	for _, c := range cases {
+		reached, failed := 0, 0
+		for seed := int64(0); seed < 20; seed++ {
+			a := randomMap(40, 30, c.p, seed)
+			if c.oneWay > 0 {
+				randomOneWay(a, c.oneWay, seed)
+			}
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < 5; i++ {
+				s, _ := a.samplePoint(rnd)
+				g, _ := a.samplePoint(rnd)
+				f, _ := a.Dijkstra(s[0], s[1])
+				opt := f.Cost(g[0], g[1])
+				route, err := a.PlanBidirectional(s[0], s[1], g[0], g[1], 1)
+				if math.IsInf(opt, 1) {
+					failed++
+					if err == nil {
+						t.Errorf("%s seed %d: route %v->%v to unreachable goal", c.name, seed, s, g)
+					}
+					continue
+				}
+				reached++
+				if err != nil {
+					t.Errorf("%s seed %d: %v->%v: %v", c.name, seed, s, g, err)
+					continue
+				}
+				cost, blocked := a.RouteCost(route)
+				if blocked >= 0 || math.Abs(cost-opt) > 1e-9 {
+					t.Errorf("%s seed %d: %v->%v cost %f (blocked %d), optimal %f", c.name, seed, s, g, cost, blocked, opt)
+				}
+				if route[0] != g || route[len(route)-1] != s {
+					t.Errorf("%s seed %d: route from %v to %v", c.name, seed, route[len(route)-1], route[0])
+				}
+			}
+		}
+		if reached == 0 {
+			t.Errorf("%s: %d reached, %d unreachable cases", c.name, reached, failed)
+		}
+	}
+	a := randomMap(40, 30, 0.1, 1)
+	if _, err := a.PlanBidirectional(-1, 0, 5, 5, 1); err == nil {
+		t.Error("start out of the map is accepted")
+	}
+}
+
+func TestPlanBidirectionalUnreachable(t *testing.T) {
+	a := laneMap()
+	a.AddObstacles([][2]int{{0, 4}, {1, 4}, {18, 4}, {19, 4}}) // close the wall
+	if _, err := a.PlanBidirectional(5, 2, 5, 6, 1); err == nil {
+		t.Error("route through the wall is found")
+	}
+	// the bottom lane is reached only against the one-way rule
+	a = laneMap()
+	a.Directions = NewDirectionMap(a)
+	for _, x := range []int{0, 1, 18, 19} {
+		a.Directions.SetOneWay(x, 4, -math.Pi/2, 0) // upward only
+	}
+	if _, err := a.PlanBidirectional(5, 2, 5, 6, 1); err == nil {
+		t.Error("route against one-way rule is found")
+	}
+	route, err := a.PlanBidirectional(5, 6, 5, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := a.Dijkstra(5, 6)
+	if c, _ := a.RouteCost(route); math.Abs(c-f.Cost(5, 2)) > 1e-9 {
+		t.Errorf("cost %f, optimal %f", c, f.Cost(5, 2))
+	}
+}
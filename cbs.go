@@ -0,0 +1,210 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Multi-agent path finding by Conflict-Based Search (Sharon et al. 2015)
+// low level is space-time A* on the weighted grid with vertex and edge (swap) constraints.
+
+// Agent is a robot with start and goal cell
+type Agent struct {
+	Start [2]int
+	Goal  [2]int
+}
+
+// Conflict between two agents (Edge is true for swap conflict)
+type Conflict struct {
+	A1, A2 int
+	T      int    // time of the conflict (swap: from T to T+1)
+	Cell   [2]int // cell of A1 at T
+	Cell2  [2]int // cell of A1 at T+1 (swap conflict)
+	Edge   bool
+}
+
+// CBS solver, parameters can be changed after NewCBS
+type CBS struct {
+	Astar    *Astar
+	Weight   float64 // heuristic weight of low level search (1.0: optimal)
+	WaitCost float64 // cost of waiting one step
+	MaxTime  int     // time horizon of low level search
+	MaxNodes int     // max number of high level nodes
+}
+
+// NewCBS returns solver with default parameters
+func NewCBS(a *Astar) *CBS {
+	return &CBS{
+		Astar:    a,
+		Weight:   1.0,
+		WaitCost: 1.0,
+		MaxTime:  2000,
+		MaxNodes: 5000,
+	}
+}
+
+// agentConstraints are the constraints of one agent in a CBS node
+type agentConstraints struct {
+	vertex map[[3]int]bool // x, y, t
+	edge   map[[5]int]bool // x, y, nx, ny, t
+	last   map[[2]int]int  // last constrained time of the cell
+}
+
+func newAgentConstraints() *agentConstraints {
+	return &agentConstraints{
+		vertex: make(map[[3]int]bool),
+		edge:   make(map[[5]int]bool),
+		last:   make(map[[2]int]int),
+	}
+}
+
+func (c *agentConstraints) copy() *agentConstraints {
+	n := newAgentConstraints()
+	for k := range c.vertex {
+		n.vertex[k] = true
+	}
+	for k := range c.edge {
+		n.edge[k] = true
+	}
+	for k, v := range c.last {
+		n.last[k] = v
+	}
+	return n
+}
+
+func (c *agentConstraints) addVertex(x, y, t int) {
+	c.vertex[[3]int{x, y, t}] = true
+	if l, ok := c.last[[2]int{x, y}]; !ok || t > l {
+		c.last[[2]int{x, y}] = t
+	}
+}
+
+func (c *agentConstraints) vertexBlocked(x, y, t int) bool {
+	return c.vertex[[3]int{x, y, t}]
+}
+
+func (c *agentConstraints) edgeBlocked(x, y, nx, ny, t int) bool {
+	return c.edge[[5]int{x, y, nx, ny, t}]
+}
+
+func (c *agentConstraints) lastBlocked(x, y int) int {
+	if l, ok := c.last[[2]int{x, y}]; ok {
+		return l
+	}
+	return -1
+}
+
+type cbsNode struct {
+	constraints []*agentConstraints
+	routes      []TimedRoute
+	costs       []float64
+	cost        float64
+}
+
+// FindConflict returns the first conflict among timed routes (ok is false if none)
+func FindConflict(routes []TimedRoute) (Conflict, bool) {
+	maxT := 0
+	for _, r := range routes {
+		if len(r) > maxT {
+			maxT = len(r)
+		}
+	}
+	for t := 0; t < maxT; t++ {
+		for i := 0; i < len(routes); i++ {
+			for j := i + 1; j < len(routes); j++ {
+				pi, pj := routes[i].At(t), routes[j].At(t)
+				if pi == pj {
+					return Conflict{A1: i, A2: j, T: t, Cell: pi}, true
+				}
+				qi, qj := routes[i].At(t+1), routes[j].At(t+1)
+				if pi == qj && pj == qi && pi != qi {
+					return Conflict{A1: i, A2: j, T: t, Cell: pi, Cell2: qi, Edge: true}, true
+				}
+			}
+		}
+	}
+	return Conflict{}, false
+}
+
+func (cb *CBS) lowLevel(ag Agent, c *agentConstraints, h []float64) (TimedRoute, float64, error) {
+	return cb.Astar.spaceTimePlan(ag.Start[0], ag.Start[1], ag.Goal[0], ag.Goal[1], cb.Weight, cb.WaitCost, cb.MaxTime, c, h)
+}
+
+// Solve finds collision-free timed routes for agents, total cost is sum of route costs.
+func (cb *CBS) Solve(agents []Agent) ([]TimedRoute, float64, error) {
+	a := cb.Astar
+	for i, ag := range agents {
+		for j := 0; j < i; j++ {
+			if agents[j].Start == ag.Start {
+				return nil, 0, fmt.Errorf("agent %d and %d have same start (%d, %d)", j, i, ag.Start[0], ag.Start[1])
+			}
+			if agents[j].Goal == ag.Goal {
+				return nil, 0, fmt.Errorf("agent %d and %d have same goal (%d, %d)", j, i, ag.Goal[0], ag.Goal[1])
+			}
+		}
+	}
+	// true cost-to-go of each agent as heuristic
+	hs := make([][]float64, len(agents))
+	root := &cbsNode{
+		constraints: make([]*agentConstraints, len(agents)),
+		routes:      make([]TimedRoute, len(agents)),
+		costs:       make([]float64, len(agents)),
+	}
+	for i, ag := range agents {
+		if !a.verifyPoint(ag.Goal[0], ag.Goal[1]) {
+			return nil, 0, fmt.Errorf("agent %d: goal point (%d, %d) is not verified", i, ag.Goal[0], ag.Goal[1])
+		}
+		hs[i] = a.costToGo(ag.Goal[0], ag.Goal[1])
+		root.constraints[i] = newAgentConstraints()
+		r, c, err := cb.lowLevel(ag, root.constraints[i], hs[i])
+		if err != nil {
+			return nil, 0, fmt.Errorf("agent %d: %v", i, err)
+		}
+		root.routes[i] = r
+		root.costs[i] = c
+		root.cost += c
+	}
+
+	nodes := []*cbsNode{root}
+	pq := &priorityQueue{}
+	pq.push(0, root.cost)
+	for pq.Len() > 0 && len(nodes) <= cb.MaxNodes {
+		n := nodes[pq.pop().key]
+		cf, ok := FindConflict(n.routes)
+		if !ok {
+			return n.routes, n.cost, nil
+		}
+		for _, ai := range []int{cf.A1, cf.A2} {
+			child := &cbsNode{
+				constraints: append([]*agentConstraints(nil), n.constraints...),
+				routes:      append([]TimedRoute(nil), n.routes...),
+				costs:       append([]float64(nil), n.costs...),
+			}
+			c := n.constraints[ai].copy()
+			switch {
+			case !cf.Edge:
+				c.addVertex(cf.Cell[0], cf.Cell[1], cf.T)
+			case ai == cf.A1:
+				c.edge[[5]int{cf.Cell[0], cf.Cell[1], cf.Cell2[0], cf.Cell2[1], cf.T}] = true
+			default:
+				c.edge[[5]int{cf.Cell2[0], cf.Cell2[1], cf.Cell[0], cf.Cell[1], cf.T}] = true
+			}
+			child.constraints[ai] = c
+			r, cost, err := cb.lowLevel(agents[ai], c, hs[ai])
+			if err != nil {
+				continue // no route under the constraints
+			}
+			child.routes[ai] = r
+			child.costs[ai] = cost
+			for _, ci := range child.costs {
+				child.cost += ci
+			}
+			nodes = append(nodes, child)
+			pq.push(len(nodes)-1, child.cost)
+		}
+	}
+	if pq.Len() == 0 {
+		return nil, math.Inf(1), fmt.Errorf("no collision-free routes for %d agents", len(agents))
+	}
+	return nil, math.Inf(1), fmt.Errorf("no collision-free routes for %d agents in %d nodes", len(agents), cb.MaxNodes)
+}
@@ -0,0 +1,73 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+// corridorMap is 11x3 corridor (y=1) with a passing bay at (5,2)
+func corridorMap() *Astar {
+	var objects [][2]int
+	for x := 0; x < 11; x++ {
+		objects = append(objects, [2]int{x, 0})
+		if x != 5 {
+			objects = append(objects, [2]int{x, 2})
+		}
+	}
+	return NewWeightedAstar(objects, 11, 3, 0)
+}
+
+func checkTimedRoutes(t *testing.T, a *Astar, agents []Agent, routes []TimedRoute, total, waitCost float64) {
+	t.Helper()
+	if cf, ok := FindConflict(routes); ok {
+		t.Errorf("conflict %+v", cf)
+	}
+	sum := 0.0
+	for i, r := range routes {
+		if r[0] != agents[i].Start || r[len(r)-1] != agents[i].Goal {
+			t.Errorf("agent %d: route from %v to %v", i, r[0], r[len(r)-1])
+		}
+		sum += a.timedCost(r, waitCost)
+	}
+	if math.Abs(sum-total) > 1e-9 {
+		t.Errorf("total cost %f, timedCost %f", total, sum)
+	}
+}
+
+func TestCBSCorridorSwap(t *testing.T) {
+	a := corridorMap()
+	agents := []Agent{{Start: [2]int{0, 1}, Goal: [2]int{10, 1}}, {Start: [2]int{10, 1}, Goal: [2]int{0, 1}}}
+	cb := NewCBS(a)
+	routes, total, err := cb.Solve(agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTimedRoutes(t, a, agents, routes, total, cb.WaitCost)
+	// one agent gets into the bay, the other passes by
+	bay := false
+	for _, r := range routes {
+		for _, p := range r {
+			bay = bay || p == [2]int{5, 2}
+		}
+	}
+	if !bay {
+		t.Errorf("no agent uses the bay: %v", routes)
+	}
+}
+
+func TestSpaceTimeGoalBlocked(t *testing.T) {
+	a := corridorMap()
+	c := newAgentConstraints()
+	c.addVertex(3, 1, 5) // goal is used by other agent at time 5
+	tr, cost, err := a.spaceTimePlan(0, 1, 3, 1, 1, 3, 100, c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.At(5) == [2]int{3, 1} || tr[len(tr)-1] != [2]int{3, 1} {
+		t.Errorf("route %v", tr)
+	}
+	// waits cost 3, so moving around (7 moves) is the best
+	if c := a.timedCost(tr, 3); c != cost || cost != 7 {
+		t.Errorf("cost %f, timedCost %f, want 7 (route %v)", cost, c, tr)
+	}
+}
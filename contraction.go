@@ -0,0 +1,445 @@
+package astar_wr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Contraction hierarchy (Geisberger et al. 2008) on the roadmap graph
+// nodes are contracted in order of edge difference, queries are bidirectional upward Dijkstra.
+
+// CHEdge is an edge of the hierarchy, a shortcut is made of two child edges
+type CHEdge struct {
+	From, To int
+	Cost     float64
+	Edge     int    // roadmap edge (-1: shortcut)
+	Child    [2]int // child edges of shortcut
+}
+
+// ContractionHierarchy is the precomputed index, it can be saved and loaded as JSON
+type ContractionHierarchy struct {
+	Roadmap *Roadmap
+	Rank    []int
+	Edges   []CHEdge
+
+	up   [][]int // node -> edges to higher rank
+	down [][]int // node -> edges from higher rank
+}
+
+// witnessLimit is max number of settled nodes in a witness search
+const witnessLimit = 100
+
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+type chBuilder struct {
+	ch         *ContractionHierarchy
+	out, in    []map[int]int // best edge between node pairs
+	contracted []bool
+	level      []int
+}
+
+func (b *chBuilder) addEdge(e CHEdge) {
+	if e.From == e.To {
+		return
+	}
+	if i, ok := b.out[e.From][e.To]; ok && b.ch.Edges[i].Cost <= e.Cost {
+		return
+	}
+	b.ch.Edges = append(b.ch.Edges, e)
+	b.out[e.From][e.To] = len(b.ch.Edges) - 1
+	b.in[e.To][e.From] = len(b.ch.Edges) - 1
+}
+
+// witness returns costs from u without passing v, up to limit
+func (b *chBuilder) witness(u, v int, limit float64) map[int]float64 {
+	dist := map[int]float64{u: 0}
+	pq := &priorityQueue{}
+	pq.push(u, 0)
+	for settled := 0; pq.Len() > 0 && settled < witnessLimit; settled++ {
+		it := pq.pop()
+		if it.priority > dist[it.key] || it.priority > limit {
+			continue
+		}
+		for w, e := range b.out[it.key] {
+			if w == v || b.contracted[w] {
+				continue
+			}
+			nd := it.priority + b.ch.Edges[e].Cost
+			if d, ok := dist[w]; !ok || nd < d {
+				dist[w] = nd
+				pq.push(w, nd)
+			}
+		}
+	}
+	return dist
+}
+
+// contract counts (and adds if apply) shortcuts needed to remove v
+func (b *chBuilder) contract(v int, apply bool) int {
+	count := 0
+	for _, u := range sortedKeys(b.in[v]) {
+		if b.contracted[u] {
+			continue
+		}
+		c1 := b.ch.Edges[b.in[v][u]].Cost
+		limit := 0.0
+		for w, e := range b.out[v] {
+			if !b.contracted[w] && w != u {
+				limit = math.Max(limit, c1+b.ch.Edges[e].Cost)
+			}
+		}
+		wd := b.witness(u, v, limit)
+		for _, w := range sortedKeys(b.out[v]) {
+			if b.contracted[w] || w == u {
+				continue
+			}
+			e2 := b.out[v][w]
+			c := c1 + b.ch.Edges[e2].Cost
+			if d, ok := wd[w]; ok && d <= c {
+				continue
+			}
+			count++
+			if apply {
+				b.addEdge(CHEdge{From: u, To: w, Cost: c, Edge: -1, Child: [2]int{b.in[v][u], e2}})
+			}
+		}
+	}
+	return count
+}
+
+func (b *chBuilder) priority(v int) float64 {
+	deg := 0
+	for w := range b.out[v] {
+		if !b.contracted[w] {
+			deg++
+		}
+	}
+	for u := range b.in[v] {
+		if !b.contracted[u] {
+			deg++
+		}
+	}
+	return float64(b.contract(v, false)-deg) + float64(b.level[v])
+}
+
+// BuildContractionHierarchy contracts all nodes of the roadmap
+func BuildContractionHierarchy(r *Roadmap) *ContractionHierarchy {
+	n := len(r.Nodes)
+	ch := &ContractionHierarchy{Roadmap: r, Rank: make([]int, n)}
+	b := &chBuilder{
+		ch:         ch,
+		out:        make([]map[int]int, n),
+		in:         make([]map[int]int, n),
+		contracted: make([]bool, n),
+		level:      make([]int, n),
+	}
+	for i := 0; i < n; i++ {
+		b.out[i] = make(map[int]int)
+		b.in[i] = make(map[int]int)
+	}
+	for i, e := range r.Edges {
+		b.addEdge(CHEdge{From: e.From, To: e.To, Cost: e.Cost, Edge: i, Child: [2]int{-1, -1}})
+	}
+	pq := &priorityQueue{}
+	for v := 0; v < n; v++ {
+		pq.push(v, b.priority(v))
+	}
+	for rank := 0; pq.Len() > 0; {
+		v := pq.pop().key
+		if b.contracted[v] {
+			continue
+		}
+		// lazy update of the priority
+		if p := b.priority(v); pq.Len() > 0 && p > (*pq)[0].priority {
+			pq.push(v, p)
+			continue
+		}
+		b.contract(v, true)
+		b.contracted[v] = true
+		ch.Rank[v] = rank
+		rank++
+		for w := range b.out[v] {
+			b.level[w]++
+		}
+		for u := range b.in[v] {
+			b.level[u]++
+		}
+	}
+	ch.index()
+	return ch
+}
+
+// index makes upward and downward adjacency
+func (ch *ContractionHierarchy) index() {
+	n := len(ch.Rank)
+	ch.up = make([][]int, n)
+	ch.down = make([][]int, n)
+	for i, e := range ch.Edges {
+		if ch.Rank[e.To] > ch.Rank[e.From] {
+			ch.up[e.From] = append(ch.up[e.From], i)
+		} else {
+			ch.down[e.To] = append(ch.down[e.To], i)
+		}
+	}
+}
+
+// unpack appends roadmap edges of CH edge e
+func (ch *ContractionHierarchy) unpack(e int, edges []int) []int {
+	if ch.Edges[e].Edge >= 0 {
+		return append(edges, ch.Edges[e].Edge)
+	}
+	edges = ch.unpack(ch.Edges[e].Child[0], edges)
+	return ch.unpack(ch.Edges[e].Child[1], edges)
+}
+
+// Query returns roadmap edges of the shortest path from node s to node t and its cost
+func (ch *ContractionHierarchy) Query(s, t int) ([]int, float64, error) {
+	n := len(ch.Rank)
+	if s < 0 || t < 0 || s >= n || t >= n {
+		return nil, 0, fmt.Errorf("wrong node %d or %d", s, t)
+	}
+	edges, cost, _, _, ok := ch.query(map[int]float64{s: 0}, map[int]float64{t: 0})
+	if !ok {
+		return nil, math.Inf(1), fmt.Errorf("node %d is not reachable from node %d", t, s)
+	}
+	return edges, cost, nil
+}
+
+// query searches from sources (node -> initial cost) to targets (node -> cost after the node).
+// it returns roadmap edges, total cost and the source and target of the path.
+func (ch *ContractionHierarchy) query(src, dst map[int]float64) ([]int, float64, int, int, bool) {
+	dist := [2]map[int]float64{{}, {}}
+	par := [2]map[int]int{{}, {}}
+	pq := [2]*priorityQueue{{}, {}}
+	for d, seeds := range [2]map[int]float64{src, dst} {
+		for _, v := range sortedNodes(seeds) {
+			dist[d][v] = seeds[v]
+			par[d][v] = -1
+			pq[d].push(v, seeds[v])
+		}
+	}
+	best, meet := math.Inf(1), -1
+	for {
+		d := -1
+		for i := 0; i < 2; i++ {
+			if pq[i].Len() > 0 && (*pq[i])[0].priority < best && (d < 0 || (*pq[i])[0].priority < (*pq[d])[0].priority) {
+				d = i
+			}
+		}
+		if d < 0 {
+			break
+		}
+		it := pq[d].pop()
+		if it.priority > dist[d][it.key] {
+			continue
+		}
+		if od, ok := dist[1-d][it.key]; ok && it.priority+od < best {
+			best, meet = it.priority+od, it.key
+		}
+		adj := ch.up[it.key]
+		if d == 1 {
+			adj = ch.down[it.key]
+		}
+		for _, ei := range adj {
+			e := ch.Edges[ei]
+			w := e.To
+			if d == 1 {
+				w = e.From
+			}
+			nd := it.priority + e.Cost
+			if old, ok := dist[d][w]; !ok || nd < old {
+				dist[d][w] = nd
+				par[d][w] = ei
+				pq[d].push(w, nd)
+			}
+		}
+	}
+	if meet < 0 {
+		return nil, math.Inf(1), -1, -1, false
+	}
+	var fwd []int // CH edges from s to meet
+	s := meet
+	for ; par[0][s] != -1; s = ch.Edges[par[0][s]].From {
+		fwd = append([]int{par[0][s]}, fwd...)
+	}
+	t := meet
+	for ; par[1][t] != -1; t = ch.Edges[par[1][t]].To {
+		fwd = append(fwd, par[1][t])
+	}
+	var edges []int
+	for _, e := range fwd {
+		edges = ch.unpack(e, edges)
+	}
+	return edges, best, s, t, true
+}
+
+func sortedNodes(m map[int]float64) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// attachRadius is the first half size of the window searched around start / goal
+const attachRadius = 16
+
+// attachWindow is a local search around start (or goal if reverse) in [x0,x1]x[y0,y1]
+type attachWindow struct {
+	x0, y0, x1, y1 int
+	reverse        bool
+	dist           []float64
+	link           []int32
+}
+
+// cost returns cost between (x,y) and the center of the window (Inf if not reached)
+func (w *attachWindow) cost(x, y int) float64 {
+	if x < w.x0 || x > w.x1 || y < w.y0 || y > w.y1 {
+		return math.Inf(1)
+	}
+	return w.dist[(y-w.y0)*(w.x1-w.x0+1)+x-w.x0]
+}
+
+// path returns cells between the center and (x,y) in driving order
+func (w *attachWindow) path(x, y int) [][2]int {
+	ww := w.x1 - w.x0 + 1
+	var cells [][2]int
+	for i := int32((y-w.y0)*ww + x - w.x0); i != -1; i = w.link[i] {
+		cells = append(cells, [2]int{int(i)%ww + w.x0, int(i)/ww + w.y0})
+	}
+	if !w.reverse { // walked back to the start
+		for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+			cells[i], cells[j] = cells[j], cells[i]
+		}
+	}
+	return cells
+}
+
+// attach searches a window around (x,y), doubled until roadmap nodes are reached.
+// it returns the reached nodes with cost from (x,y) (to (x,y) if reverse).
+func (ch *ContractionHierarchy) attach(a *Astar, x, y int, reverse bool) (map[int]float64, *attachWindow) {
+	r := ch.Roadmap
+	for d := attachRadius; ; d *= 2 {
+		w := &attachWindow{reverse: reverse}
+		w.x0, w.y0, w.x1, w.y1 = clipRect(a, x-d, y-d, x+d, y+d)
+		w.dist, w.link = a.rectSearch(w.x0, w.y0, w.x1, w.y1, y*a.Width+x, reverse)
+		nodes := make(map[int]float64)
+		for _, i := range r.NodesIn(w.x0, w.y0, w.x1, w.y1) {
+			if c := w.cost(r.Nodes[i].X, r.Nodes[i].Y); !math.IsInf(c, 1) {
+				nodes[i] = c
+			}
+		}
+		whole := w.x0 == 0 && w.y0 == 0 && w.x1 == a.MaxX && w.y1 == a.MaxY
+		if len(nodes) > 0 || whole {
+			return nodes, w
+		}
+	}
+}
+
+// Route plans route on the grid through the roadmap (Plan format) with its cost.
+// start and goal are connected to roadmap nodes by local search in windows around them,
+// the route inside the start window is used if it is cheaper.
+// the index is stale after the map is changed (Astar.Version), build it again.
+func (ch *ContractionHierarchy) Route(a *Astar, sx, sy, gx, gy int) ([][2]int, float64, error) {
+	r := ch.Roadmap
+	if a.Width != r.Width || a.Height != r.Height {
+		return nil, 0, fmt.Errorf("map size %dx%d is different from the index %dx%d", a.Width, a.Height, r.Width, r.Height)
+	}
+	if a.Version != r.Version {
+		return nil, 0, fmt.Errorf("stale index: map version %d, index version %d", a.Version, r.Version)
+	}
+	if !a.verifyPoint(sx, sy) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	src, ws := ch.attach(a, sx, sy, false)
+	dst, wg := ch.attach(a, gx, gy, true)
+	var cells [][2]int // driving order
+	cost := ws.cost(gx, gy)
+	if edges, c, s, t, ok := ch.query(src, dst); ok && c < cost {
+		cost = c
+		cells = ws.path(r.Nodes[s].X, r.Nodes[s].Y)
+		for _, e := range edges {
+			cells = append(cells, r.Edges[e].Path[1:]...)
+		}
+		cells = append(cells, wg.path(r.Nodes[t].X, r.Nodes[t].Y)[1:]...)
+	} else if !math.IsInf(cost, 1) {
+		cells = ws.path(gx, gy)
+	} else {
+		return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): goal is not reachable through the roadmap", sx, sy, gx, gy)
+	}
+	route := make([][2]int, len(cells))
+	for i, p := range cells { // Plan format
+		route[len(cells)-1-i] = p
+	}
+	return route, cost, a.checkUnknown(route)
+}
+
+// Save writes the index as JSON
+func (ch *ContractionHierarchy) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ch)
+}
+
+// LoadContractionHierarchy reads the index written by Save
+func LoadContractionHierarchy(rd io.Reader) (*ContractionHierarchy, error) {
+	ch := &ContractionHierarchy{}
+	if err := json.NewDecoder(rd).Decode(ch); err != nil {
+		return nil, err
+	}
+	if ch.Roadmap == nil || len(ch.Rank) != len(ch.Roadmap.Nodes) {
+		return nil, fmt.Errorf("broken contraction hierarchy index")
+	}
+	r := ch.Roadmap
+	n := len(r.Nodes)
+	in := func(p [2]int) bool {
+		return p[0] >= 0 && p[1] >= 0 && p[0] < r.Width && p[1] < r.Height
+	}
+	for i, nd := range r.Nodes {
+		if !in([2]int{nd.X, nd.Y}) {
+			return nil, fmt.Errorf("broken roadmap node %d (%d, %d)", i, nd.X, nd.Y)
+		}
+	}
+	for i, e := range r.Edges {
+		if e.From < 0 || e.To < 0 || e.From >= n || e.To >= n || len(e.Path) == 0 {
+			return nil, fmt.Errorf("broken roadmap edge %d: %d->%d", i, e.From, e.To)
+		}
+		for _, p := range e.Path {
+			if !in(p) {
+				return nil, fmt.Errorf("broken roadmap edge %d: cell (%d, %d) is out of the map", i, p[0], p[1])
+			}
+		}
+	}
+	for i, e := range ch.Edges {
+		if e.From < 0 || e.To < 0 || e.From >= n || e.To >= n {
+			return nil, fmt.Errorf("broken contraction hierarchy edge %d: %d->%d", i, e.From, e.To)
+		}
+		if e.Edge >= 0 {
+			if e.Edge >= len(r.Edges) || r.Edges[e.Edge].From != e.From || r.Edges[e.Edge].To != e.To {
+				return nil, fmt.Errorf("broken contraction hierarchy edge %d: roadmap edge %d", i, e.Edge)
+			}
+			continue
+		}
+		// children are added before the shortcut, so unpack always ends
+		c0, c1 := e.Child[0], e.Child[1]
+		if e.Edge != -1 || c0 < 0 || c1 < 0 || c0 >= i || c1 >= i ||
+			ch.Edges[c0].From != e.From || ch.Edges[c0].To != ch.Edges[c1].From || ch.Edges[c1].To != e.To {
+			return nil, fmt.Errorf("broken contraction hierarchy edge %d: children %d, %d", i, c0, c1)
+		}
+	}
+	r.index()
+	ch.index()
+	return ch, nil
+}
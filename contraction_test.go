@@ -0,0 +1,161 @@
+package astar_wr
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// roadmapCosts returns costs from node s on the roadmap (Dijkstra)
+func roadmapCosts(r *Roadmap, s int) []float64 {
+	dist := make([]float64, len(r.Nodes))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[s] = 0
+	pq := &priorityQueue{}
+	pq.push(s, 0)
+	for pq.Len() > 0 {
+		it := pq.pop()
+		if it.priority > dist[it.key] {
+			continue
+		}
+		for _, ei := range r.Out(it.key) {
+			e := r.Edges[ei]
+			if nd := it.priority + e.Cost; nd < dist[e.To] {
+				dist[e.To] = nd
+				pq.push(e.To, nd)
+			}
+		}
+	}
+	return dist
+}
+
+func shelfHierarchy(t *testing.T) (*Astar, *ContractionHierarchy) {
+	t.Helper()
+	a := shelfMap()
+	return a, BuildContractionHierarchy(mustRoadmap(t, a))
+}
+
+func mustRoadmap(t *testing.T, a *Astar) *Roadmap {
+	t.Helper()
+	r, err := ExtractRoadmap(a, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestContractionQuery(t *testing.T) {
+	_, ch := shelfHierarchy(t)
+	r := ch.Roadmap
+	for s := range r.Nodes {
+		want := roadmapCosts(r, s)
+		for g := range r.Nodes {
+			edges, cost, err := ch.Query(s, g)
+			if err != nil || math.Abs(cost-want[g]) > 1e-9 {
+				t.Fatalf("Query(%d, %d) = %f (%v), want %f", s, g, cost, err, want[g])
+			}
+			sum, u := 0.0, s
+			for _, e := range edges {
+				if r.Edges[e].From != u {
+					t.Fatalf("Query(%d, %d): edge %d does not start at %d", s, g, e, u)
+				}
+				sum, u = sum+r.Edges[e].Cost, r.Edges[e].To
+			}
+			if u != g || math.Abs(sum-cost) > 1e-9 {
+				t.Fatalf("Query(%d, %d): edges end at %d with cost %f", s, g, u, sum)
+			}
+		}
+	}
+}
+
+func TestContractionRoute(t *testing.T) {
+	a, ch := shelfHierarchy(t)
+	for _, c := range [][4]int{
+		{3, 3, 57, 37},   // around all shelves
+		{12, 20, 30, 30}, // between aisles
+		{20, 37, 22, 36}, // near each other
+		{5, 12, 5, 12},
+	} {
+		route, cost, err := ch.Route(a, c[0], c[1], c[2], c[3])
+		if err != nil {
+			t.Fatalf("Route%v: %v", c, err)
+		}
+		if route[0] != [2]int{c[2], c[3]} || route[len(route)-1] != [2]int{c[0], c[1]} {
+			t.Errorf("Route%v from %v to %v", c, route[len(route)-1], route[0])
+		}
+		if rc, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(rc-cost) > 1e-9 {
+			t.Errorf("Route%v: route cost %f (blocked %d), cost %f", c, rc, blocked, cost)
+		}
+		f, _ := a.Dijkstra(c[0], c[1])
+		if opt := f.Cost(c[2], c[3]); cost < opt-1e-9 || cost > 1.5*opt+2 {
+			t.Errorf("Route%v: cost %f, optimal %f", c, cost, opt)
+		}
+	}
+	if _, _, err := ch.Route(a, 8, 10, 20, 20); err == nil {
+		t.Error("start on a shelf is accepted")
+	}
+}
+
+func TestContractionStale(t *testing.T) {
+	a, ch := shelfHierarchy(t)
+	if _, _, err := ch.Route(a, 3, 3, 57, 37); err != nil {
+		t.Fatal(err)
+	}
+	a.AddObstacle(30, 2)
+	if _, _, err := ch.Route(a, 3, 3, 57, 37); err == nil {
+		t.Error("stale index is used after the map is changed")
+	}
+	ch = BuildContractionHierarchy(mustRoadmap(t, a))
+	if _, _, err := ch.Route(a, 3, 3, 57, 37); err != nil {
+		t.Errorf("rebuilt index: %v", err)
+	}
+}
+
+func TestContractionLoad(t *testing.T) {
+	a, ch := shelfHierarchy(t)
+	var buf bytes.Buffer
+	if err := ch.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadContractionHierarchy(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, c1, _ := ch.Route(a, 3, 3, 57, 37)
+	r2, c2, err := loaded.Route(a, 3, 3, 57, 37)
+	if err != nil || c1 != c2 || len(r1) != len(r2) {
+		t.Errorf("loaded index: cost %f (%v), want %f", c2, err, c1)
+	}
+
+	shortcut := -1
+	for i, e := range ch.Edges {
+		if e.Edge < 0 {
+			shortcut = i
+			break
+		}
+	}
+	if shortcut < 0 {
+		t.Fatal("no shortcut in the hierarchy")
+	}
+	for name, broken := range map[string]func(c *ContractionHierarchy){
+		"roadmap edge": func(c *ContractionHierarchy) { c.Edges[0].Edge = len(c.Roadmap.Edges) },
+		"wrong edge":   func(c *ContractionHierarchy) { c.Edges[0].Edge = 1 },
+		"child":        func(c *ContractionHierarchy) { c.Edges[shortcut].Child[0] = len(c.Edges) },
+		"child chain":  func(c *ContractionHierarchy) { c.Edges[shortcut].Child[1] = c.Edges[shortcut].Child[0] },
+		"edge node":    func(c *ContractionHierarchy) { c.Roadmap.Edges[0].To = len(c.Roadmap.Nodes) },
+		"edge path":    func(c *ContractionHierarchy) { c.Roadmap.Edges[0].Path = [][2]int{{-1, 0}} },
+		"node":         func(c *ContractionHierarchy) { c.Roadmap.Nodes[0].X = c.Roadmap.Width },
+	} {
+		_, c := shelfHierarchy(t)
+		broken(c)
+		buf.Reset()
+		if err := c.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadContractionHierarchy(&buf); err == nil {
+			t.Errorf("broken %s is loaded", name)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package astar_wr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// All-pairs route cost matrix between named locations (charging stations, shelf faces, docks)
+
+// NamedPoint is a named location on the map
+type NamedPoint struct {
+	Name string `json:"name"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// CostMatrix is route cost between named points, Costs[i][j] is from Points[i] to Points[j] (Inf: no route)
+type CostMatrix struct {
+	Points  []NamedPoint
+	Costs   [][]float64
+	Version uint64 // map version of the computation
+	index   map[string]int
+}
+
+// CostMatrix computes the cost matrix with one Dijkstra search per point,
+// searches run in parallel by workers goroutines (0: number of CPUs).
+func (a *Astar) CostMatrix(points []NamedPoint, workers int) (*CostMatrix, error) {
+	m := &CostMatrix{
+		Points:  points,
+		Costs:   make([][]float64, len(points)),
+		Version: a.Version,
+		index:   make(map[string]int),
+	}
+	for i, p := range points {
+		if _, ok := m.index[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate point name %q", p.Name)
+		}
+		if !a.verifyPoint(p.X, p.Y) {
+			return nil, fmt.Errorf("point %q (%d, %d) is not verified", p.Name, p.X, p.Y)
+		}
+		m.index[p.Name] = i
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f := a.dijkstra([][2]int{{points[i].X, points[i].Y}}, false)
+				row := make([]float64, len(points))
+				for j, q := range points {
+					row[j] = f.Cost(q.X, q.Y)
+				}
+				m.Costs[i] = row
+			}
+		}()
+	}
+	for i := range points {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return m, nil
+}
+
+// Cost returns route cost between named points (ok is false if a name is unknown)
+func (m *CostMatrix) Cost(from, to string) (float64, bool) {
+	i, ok1 := m.index[from]
+	j, ok2 := m.index[to]
+	if !ok1 || !ok2 {
+		return math.Inf(1), false
+	}
+	return m.Costs[i][j], true
+}
+
+// WriteCSV writes the matrix with names as header row and first column (unreachable is +Inf)
+func (m *CostMatrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rec := make([]string, len(m.Points)+1)
+	for j, p := range m.Points {
+		rec[j+1] = p.Name
+	}
+	if err := cw.Write(rec); err != nil {
+		return err
+	}
+	for i, p := range m.Points {
+		rec[0] = p.Name
+		for j, c := range m.Costs[i] {
+			rec[j+1] = strconv.FormatFloat(c, 'f', -1, 64)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type costMatrixJSON struct {
+	Version uint64       `json:"version"`
+	Points  []NamedPoint `json:"points"`
+	Costs   [][]*float64 `json:"costs"` // null: no route
+}
+
+// WriteJSON writes the matrix as JSON (unreachable is null)
+func (m *CostMatrix) WriteJSON(w io.Writer) error {
+	js := costMatrixJSON{Version: m.Version, Points: m.Points, Costs: make([][]*float64, len(m.Costs))}
+	for i, row := range m.Costs {
+		js.Costs[i] = make([]*float64, len(row))
+		for j := range row {
+			if !math.IsInf(row[j], 1) {
+				js.Costs[i][j] = &row[j]
+			}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(js)
+}
+
+// CostMatrixCache keeps computed matrices until the map version changes
+type CostMatrixCache struct {
+	Astar    *Astar
+	Workers  int
+	mu       sync.Mutex
+	version  uint64
+	matrices map[string]*CostMatrix
+}
+
+// NewCostMatrixCache returns empty cache for the map
+func NewCostMatrixCache(a *Astar, workers int) *CostMatrixCache {
+	return &CostMatrixCache{Astar: a, Workers: workers, version: a.Version, matrices: make(map[string]*CostMatrix)}
+}
+
+// Get returns cached matrix of the points, computed again if the map is changed
+func (c *CostMatrixCache) Get(points []NamedPoint) (*CostMatrix, error) {
+	var sb strings.Builder
+	for _, p := range points {
+		fmt.Fprintf(&sb, "%s\x00%d,%d\x00", p.Name, p.X, p.Y)
+	}
+	key := sb.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != c.Astar.Version {
+		c.matrices = make(map[string]*CostMatrix)
+		c.version = c.Astar.Version
+	}
+	if m, ok := c.matrices[key]; ok {
+		return m, nil
+	}
+	m, err := c.Astar.CostMatrix(points, c.Workers)
+	if err != nil {
+		return nil, err
+	}
+	c.matrices[key] = m
+	return m, nil
+}
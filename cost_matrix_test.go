@@ -0,0 +1,124 @@
+package astar_wr
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// matrixPoints are points of shelfMap, "box" is walled off
+func matrixPoints(a *Astar) []NamedPoint {
+	for i := 53; i < 58; i++ {
+		a.AddObstacles([][2]int{{i, 33}, {i, 37}, {53, i - 20}, {57, i - 20}})
+	}
+	return []NamedPoint{{"dock", 2, 2}, {"shelf1", 12, 20}, {"charger", 40, 37}, {"box", 55, 35}}
+}
+
+func TestCostMatrix(t *testing.T) {
+	a := shelfMap()
+	points := matrixPoints(a)
+	m, err := a.CostMatrix(points, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range points {
+		f, _ := a.Dijkstra(p.X, p.Y)
+		for j, q := range points {
+			c, ok := m.Cost(p.Name, q.Name)
+			if !ok || c != m.Costs[i][j] || c != f.Cost(q.X, q.Y) {
+				t.Errorf("cost %s -> %s = %f, want %f", p.Name, q.Name, c, f.Cost(q.X, q.Y))
+			}
+			if inf := p.Name == "box" || q.Name == "box"; inf != math.IsInf(c, 1) && i != j {
+				t.Errorf("cost %s -> %s = %f", p.Name, q.Name, c)
+			}
+		}
+	}
+	if _, ok := m.Cost("dock", "nowhere"); ok {
+		t.Error("unknown name is found")
+	}
+	if _, err := a.CostMatrix(append(points, NamedPoint{"dock", 3, 3}), 1); err == nil {
+		t.Error("duplicate name is accepted")
+	}
+	if _, err := a.CostMatrix([]NamedPoint{{"shelf", 8, 10}}, 1); err == nil {
+		t.Error("point on a shelf is accepted")
+	}
+}
+
+func TestCostMatrixExport(t *testing.T) {
+	a := shelfMap()
+	m, err := a.CostMatrix(matrixPoints(a), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != len(m.Points)+1 || recs[0][0] != "" || recs[0][3] != "charger" {
+		t.Fatalf("csv header %v", recs[0])
+	}
+	for i, p := range m.Points {
+		if recs[i+1][0] != p.Name {
+			t.Errorf("csv row %d is %s", i, recs[i+1][0])
+		}
+		for j := range m.Points {
+			if c, err := strconv.ParseFloat(recs[i+1][j+1], 64); err != nil || c != m.Costs[i][j] {
+				t.Errorf("csv cost %d,%d = %s, want %f", i, j, recs[i+1][j+1], m.Costs[i][j])
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var js costMatrixJSON
+	if err := json.Unmarshal(buf.Bytes(), &js); err != nil {
+		t.Fatal(err)
+	}
+	if js.Version != m.Version || len(js.Points) != len(m.Points) || js.Points[3] != m.Points[3] {
+		t.Errorf("json version %d, points %v", js.Version, js.Points)
+	}
+	for i := range m.Points {
+		for j := range m.Points {
+			c := js.Costs[i][j]
+			if math.IsInf(m.Costs[i][j], 1) && c != nil || !math.IsInf(m.Costs[i][j], 1) && (c == nil || *c != m.Costs[i][j]) {
+				t.Errorf("json cost %d,%d = %v, want %f", i, j, c, m.Costs[i][j])
+			}
+		}
+	}
+}
+
+func TestCostMatrixCache(t *testing.T) {
+	a := shelfMap()
+	points := matrixPoints(a)
+	c := NewCostMatrixCache(a, 2)
+	m1, err := c.Get(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2, _ := c.Get(points); m2 != m1 {
+		t.Error("matrix is computed again without map change")
+	}
+	if m3, _ := c.Get(points[:2]); m3 == m1 || len(m3.Points) != 2 {
+		t.Error("different points return the same matrix")
+	}
+	a.SetCostRegion(36, 33, 44, 39, 50) // slow zone around the charger
+	m4, err := c.Get(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m4 == m1 || m4.Version != a.Version || m4.Version == m1.Version {
+		t.Errorf("matrix of version %d is used for version %d", m4.Version, a.Version)
+	}
+	if m4.Costs[0][2] <= m1.Costs[0][2] {
+		t.Errorf("cost dock -> charger %f, before the change %f", m4.Costs[0][2], m1.Costs[0][2])
+	}
+}
@@ -0,0 +1,361 @@
+package astar_wr
+
+import "fmt"
+
+// Layered cost map
+// each layer keeps its own grid and is combined in order into Astar.CostMap
+
+// CombineRule is how a layer is merged into the result of lower layers.
+type CombineRule int
+
+const (
+	CombineMax      CombineRule = iota // max(lower, layer)
+	CombineSum                         // lower + layer (saturated, lethal is kept)
+	CombineOverride                    // layer replaces lower where layer has a value
+)
+
+// standard layer names of NewLayeredCostMap
+const (
+	LayerStatic    = "static"
+	LayerDynamic   = "dynamic"
+	LayerInflation = "inflation"
+	LayerZones     = "zones"
+	LayerKeepOut   = "keepout"
+)
+
+const noCost = -0x8000 // no information in the layer
+
+// CostLayer is one layer of LayeredCostMap
+type CostLayer struct {
+	Name    string
+	Rule    CombineRule
+	Enabled bool
+
+	inflation int       // >0: regenerated from lethal cells of lower layers
+	grid      [][]int16 // noCost or cost (CombineSum layer may have negative cost)
+}
+
+// LayeredCostMap combines CostLayers into Astar.CostMap
+type LayeredCostMap struct {
+	Astar  *Astar
+	Layers []*CostLayer
+}
+
+func newCostLayer(name string, rule CombineRule, w, h int) *CostLayer {
+	l := &CostLayer{
+		Name:    name,
+		Rule:    rule,
+		Enabled: true,
+		grid:    make([][]int16, w),
+	}
+	for x := range l.grid {
+		l.grid[x] = make([]int16, h)
+		for y := range l.grid[x] {
+			l.grid[x][y] = noCost
+		}
+	}
+	return l
+}
+
+// Set sets cost of the cell
+func (l *CostLayer) Set(x, y int, cost byte) {
+	if x < 0 || y < 0 || x >= len(l.grid) || y >= len(l.grid[x]) {
+		return
+	}
+	l.grid[x][y] = int16(cost)
+}
+
+// Add adds (signed) cost to the cell, mainly for CombineSum layer
+func (l *CostLayer) Add(x, y int, delta int) {
+	if x < 0 || y < 0 || x >= len(l.grid) || y >= len(l.grid[x]) {
+		return
+	}
+	c := int(l.grid[x][y])
+	if c == noCost {
+		c = 0
+	}
+	c += delta
+	if c > 0xfe { // never becomes lethal by adding
+		c = 0xfe
+	} else if c < -0xff {
+		c = -0xff
+	}
+	l.grid[x][y] = int16(c)
+}
+
+// Clear removes cost of the cell from the layer
+func (l *CostLayer) Clear(x, y int) {
+	if x < 0 || y < 0 || x >= len(l.grid) || y >= len(l.grid[x]) {
+		return
+	}
+	l.grid[x][y] = noCost
+}
+
+// ClearAll removes all cost from the layer
+func (l *CostLayer) ClearAll() {
+	for x := range l.grid {
+		for y := range l.grid[x] {
+			l.grid[x][y] = noCost
+		}
+	}
+}
+
+// Get returns cost of the cell, ok is false if the layer has no value.
+func (l *CostLayer) Get(x, y int) (cost int, ok bool) {
+	if x < 0 || y < 0 || x >= len(l.grid) || y >= len(l.grid[x]) || l.grid[x][y] == noCost {
+		return 0, false
+	}
+	return int(l.grid[x][y]), true
+}
+
+func combineCost(rule CombineRule, lower byte, c int16) byte {
+	switch rule {
+	case CombineSum:
+		if lower == 0xff || c == 0xff {
+			return 0xff
+		}
+		s := int(lower) + int(c)
+		if s < 0 {
+			return 0
+		} else if s < 0xff {
+			return byte(s)
+		}
+		return 0xfe
+	case CombineOverride:
+		return byte(c)
+	}
+	if c > int16(lower) {
+		return byte(c)
+	}
+	return lower
+}
+
+// NewLayeredCostMap makes standard layers (static, dynamic, inflation, zones, keepout)
+// static layer is filled with objects (from ObjectMap), dynamic obstacles are also inflated.
+// if objects is nil, current CostMap (e.g. from GrayCostAstar) without inflation is used as static layer.
+// the map is set to Astar.Layers for live update.
+func NewLayeredCostMap(a *Astar, objects [][2]int, iteration int) *LayeredCostMap {
+	m := &LayeredCostMap{Astar: a}
+	static := m.AddLayer(LayerStatic, CombineMax)
+	if objects == nil {
+		a.staticCost(static)
+	}
+	for _, o := range objects {
+		static.Set(o[0], o[1], 0xff)
+	}
+	m.AddLayer(LayerDynamic, CombineMax)
+	m.AddInflationLayer(LayerInflation, iteration)
+	m.AddLayer(LayerZones, CombineSum)
+	m.AddLayer(LayerKeepOut, CombineOverride)
+	a.Layers = m
+	m.Update()
+	return m
+}
+
+// staticCost copies CostMap to the layer, removing inflation cost of Astar.Iteration.
+// cost under the inflation can not be recovered, such cells become 0.
+func (a *Astar) staticCost(static *CostLayer) {
+	lethal := make([][2]int, 0)
+	for x := 0; x < a.Width; x++ {
+		for y := 0; y < a.Height; y++ {
+			if a.CostMap[x][y] == 0xff {
+				lethal = append(lethal, [2]int{x, y})
+			}
+		}
+	}
+	dist := manhattanDistance(a.Width, a.Height, lethal, a.Iteration)
+	for x := 0; x < a.Width; x++ {
+		for y := 0; y < a.Height; y++ {
+			c := a.CostMap[x][y]
+			if d := dist[x][y]; d > 0 && c == inflationCost(d, a.Iteration) {
+				continue
+			}
+			if c > 0 {
+				static.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// AddLayer adds new layer on the top
+func (m *LayeredCostMap) AddLayer(name string, rule CombineRule) *CostLayer {
+	l := newCostLayer(name, rule, m.Astar.Width, m.Astar.Height)
+	m.Layers = append(m.Layers, l)
+	return l
+}
+
+// insertLayer adds new layer just above the layer named below (at the bottom if not found)
+func (m *LayeredCostMap) insertLayer(name string, rule CombineRule, below string) *CostLayer {
+	l := newCostLayer(name, rule, m.Astar.Width, m.Astar.Height)
+	i := 0
+	for j, b := range m.Layers {
+		if b.Name == below {
+			i = j + 1
+			break
+		}
+	}
+	m.Layers = append(m.Layers, nil)
+	copy(m.Layers[i+1:], m.Layers[i:])
+	m.Layers[i] = l
+	return l
+}
+
+// AddInflationLayer adds a layer which inflates lethal cells of lower layers (same cost as WeightedAstar)
+func (m *LayeredCostMap) AddInflationLayer(name string, iteration int) *CostLayer {
+	l := m.AddLayer(name, CombineMax)
+	l.inflation = iteration
+	return l
+}
+
+// Layer returns layer by name (nil if not found)
+func (m *LayeredCostMap) Layer(name string) *CostLayer {
+	for _, l := range m.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// SetRule changes combine rule of the layer
+func (m *LayeredCostMap) SetRule(name string, rule CombineRule) error {
+	l := m.Layer(name)
+	if l == nil {
+		return fmt.Errorf("no such layer %s", name)
+	}
+	l.Rule = rule
+	return nil
+}
+
+// Update recomputes whole Astar.CostMap from layers.
+func (m *LayeredCostMap) Update() {
+	m.UpdateRegion(0, 0, m.Astar.MaxX, m.Astar.MaxY)
+}
+
+// UpdateRegion recomputes Astar.CostMap in [x0,x1]x[y0,y1].
+// region is extended by inflation range, so changes are also inflated.
+// listeners of the Astar are notified with the extended region.
+func (m *LayeredCostMap) UpdateRegion(x0, y0, x1, y1 int) {
+	a := m.Astar
+	margin := 0
+	for _, l := range m.Layers {
+		if l.Enabled && l.inflation > margin {
+			margin = l.inflation
+		}
+	}
+	// cells in [r0,r1] are written, [s0,s1] are read for inflation
+	rx0, ry0, rx1, ry1 := clipRect(a, x0-margin, y0-margin, x1+margin, y1+margin)
+	if rx0 > rx1 || ry0 > ry1 { // out of map
+		return
+	}
+	sx0, sy0, sx1, sy1 := clipRect(a, rx0-margin, ry0-margin, rx1+margin, ry1+margin)
+
+	w := sx1 - sx0 + 1
+	h := sy1 - sy0 + 1
+	work := make([][]byte, w)
+	for i := range work {
+		work[i] = make([]byte, h)
+	}
+	for _, l := range m.Layers {
+		if !l.Enabled {
+			continue
+		}
+		if l.inflation > 0 {
+			l.inflate(work, sx0, sy0, rx0, ry0, rx1, ry1)
+		}
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				if c := l.grid[x+sx0][y+sy0]; c != noCost {
+					work[x][y] = combineCost(l.Rule, work[x][y], c)
+				}
+			}
+		}
+	}
+	for x := rx0; x <= rx1; x++ {
+		for y := ry0; y <= ry1; y++ {
+			a.CostMap[x][y] = work[x-sx0][y-sy0]
+		}
+	}
+	a.NotifyChanged(rx0, ry0, rx1, ry1)
+}
+
+// inflate regenerates inflation layer in [r0,r1] from lethal cells of work (offset s0)
+func (l *CostLayer) inflate(work [][]byte, sx0, sy0, rx0, ry0, rx1, ry1 int) {
+	lethal := make([][2]int, 0)
+	for x := range work {
+		for y := range work[x] {
+			if work[x][y] == 0xff {
+				lethal = append(lethal, [2]int{x, y})
+			}
+		}
+	}
+	dist := manhattanDistance(len(work), len(work[0]), lethal, l.inflation)
+	for x := rx0; x <= rx1; x++ {
+		for y := ry0; y <= ry1; y++ {
+			d := dist[x-sx0][y-sy0]
+			if d > 0 && d <= l.inflation {
+				l.grid[x][y] = int16(inflationCost(d, l.inflation))
+			} else {
+				l.grid[x][y] = noCost
+			}
+		}
+	}
+}
+
+// inflationCost is the cost WeightedAstar gives at 4-neighbor distance d after iteration
+// (up to 0xfe, inflation never becomes lethal)
+func inflationCost(d, iteration int) byte {
+	if c := NCOST * (iteration - d + 1); c < 0xff {
+		return byte(c)
+	}
+	return 0xfe
+}
+
+// manhattanDistance returns 4-neighbor distance from sources up to maxDist (-1: far)
+func manhattanDistance(w, h int, sources [][2]int, maxDist int) [][]int {
+	dist := make([][]int, w)
+	for x := range dist {
+		dist[x] = make([]int, h)
+		for y := range dist[x] {
+			dist[x][y] = -1
+		}
+	}
+	queue := make([][2]int, 0, len(sources))
+	for _, s := range sources {
+		dist[s[0]][s[1]] = 0
+		queue = append(queue, s)
+	}
+	for i := 0; i < len(queue); i++ {
+		p := queue[i]
+		d := dist[p[0]][p[1]]
+		if d >= maxDist {
+			continue
+		}
+		for _, v := range motion[:4] {
+			nx := p[0] + int(v[0])
+			ny := p[1] + int(v[1])
+			if nx < 0 || ny < 0 || nx >= w || ny >= h || dist[nx][ny] != -1 {
+				continue
+			}
+			dist[nx][ny] = d + 1
+			queue = append(queue, [2]int{nx, ny})
+		}
+	}
+	return dist
+}
+
+func clipRect(a *Astar, x0, y0, x1, y1 int) (int, int, int, int) {
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > a.MaxX {
+		x1 = a.MaxX
+	}
+	if y1 > a.MaxY {
+		y1 = a.MaxY
+	}
+	return x0, y0, x1, y1
+}
@@ -0,0 +1,96 @@
+package astar_wr
+
+import "testing"
+
+func TestCombineCost(t *testing.T) {
+	cases := []struct {
+		rule  CombineRule
+		lower byte
+		c     int16
+		want  byte
+	}{
+		{CombineMax, 10, 20, 20},
+		{CombineMax, 30, 20, 30},
+		{CombineMax, 0xff, 0, 0xff},
+		{CombineSum, 10, 20, 30},
+		{CombineSum, 250, 20, 0xfe}, // saturated, not lethal
+		{CombineSum, 0xfe, 0xfe, 0xfe},
+		{CombineSum, 0xff, -100, 0xff}, // lethal is kept
+		{CombineSum, 10, 0xff, 0xff},
+		{CombineSum, 10, -30, 0}, // negative cost
+		{CombineSum, 50, -30, 20},
+		{CombineOverride, 200, 5, 5},
+		{CombineOverride, 0xff, 0, 0},
+	}
+	for _, c := range cases {
+		if got := combineCost(c.rule, c.lower, c.c); got != c.want {
+			t.Errorf("combineCost(%d, %d, %d) = %d, want %d", c.rule, c.lower, c.c, got, c.want)
+		}
+	}
+}
+
+func TestLayerRules(t *testing.T) {
+	a := NewWeightedAstar(nil, 20, 20, 0)
+	m := NewLayeredCostMap(a, [][2]int{{5, 5}}, 0)
+	m.Layer(LayerStatic).Set(1, 1, 100)
+	m.Layer(LayerDynamic).Set(1, 1, 50) // max
+	m.Layer(LayerStatic).Set(2, 2, 100)
+	m.Layer(LayerZones).Add(2, 2, 200) // saturated
+	m.Layer(LayerStatic).Set(3, 3, 20)
+	m.Layer(LayerZones).Add(3, 3, -50)    // negative
+	m.Layer(LayerZones).Add(5, 5, -50)    // lethal is kept
+	m.Layer(LayerKeepOut).Set(4, 4, 0xff) // override
+	m.Layer(LayerStatic).Set(6, 6, 0xff)
+	m.Layer(LayerKeepOut).Set(6, 6, 0)
+	m.Update()
+	for _, c := range []struct {
+		x, y int
+		want byte
+	}{{1, 1, 100}, {2, 2, 0xfe}, {3, 3, 0}, {5, 5, 0xff}, {4, 4, 0xff}, {6, 6, 0}} {
+		if got := a.CostMap[c.x][c.y]; got != c.want {
+			t.Errorf("cost of (%d, %d) = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+	if err := m.SetRule("none", CombineSum); err == nil {
+		t.Error("SetRule of unknown layer should fail")
+	}
+}
+
+func TestUpdateRegionInflation(t *testing.T) {
+	a := NewWeightedAstar(nil, 30, 30, 0)
+	m := NewLayeredCostMap(a, nil, 3)
+	dyn := m.Layer(LayerDynamic)
+	dyn.Set(10, 10, 0xff)
+	m.UpdateRegion(10, 10, 10, 10)
+	// inflation reaches cells out of the region
+	for d := 1; d <= 3; d++ {
+		if got := a.CostMap[10+d][10]; got != inflationCost(d, 3) {
+			t.Errorf("cost at distance %d = %d, want %d", d, got, inflationCost(d, 3))
+		}
+	}
+	if a.CostMap[14][10] != 0 {
+		t.Errorf("cost at distance 4 = %d", a.CostMap[14][10])
+	}
+	// obstacle next to the region inflates into it
+	dyn.Set(20, 10, 0xff)
+	m.UpdateRegion(20, 10, 20, 10)
+	dyn.Clear(10, 10)
+	m.UpdateRegion(10, 10, 10, 10)
+	for d := 0; d <= 3; d++ {
+		if a.CostMap[10+d][10] != 0 {
+			t.Errorf("cost at distance %d = %d after clear", d, a.CostMap[10+d][10])
+		}
+	}
+	if got := a.CostMap[17][10]; got != inflationCost(3, 3) {
+		t.Errorf("cost of (17, 10) = %d, want %d", got, inflationCost(3, 3))
+	}
+}
+
+func TestInflationCostSaturates(t *testing.T) {
+	if c := inflationCost(1, 60); c != 0xfe {
+		t.Errorf("inflationCost(1, 60) = %d, want 0xfe", c)
+	}
+	if c := inflationCost(60, 60); c != NCOST {
+		t.Errorf("inflationCost(60, 60) = %d, want %d", c, NCOST)
+	}
+}
@@ -0,0 +1,362 @@
+package astar_wr
+
+import "math"
+
+// analytic curves for car-like vehicles (Dubins / Reeds-Shepp)
+// every curve is computed in the start frame, normalized by the turning radius.
+
+// curveSegment is one piece of an analytic curve.
+// kind is 'L' (left turn), 'R' (right turn) or 'S' (straight),
+// length is signed in units of the turning radius, negative means reverse.
+type curveSegment struct {
+	kind   byte
+	length float64
+}
+
+type curvePath []curveSegment
+
+const curveEps = 1e-6
+
+func (p curvePath) length() float64 {
+	l := 0.0
+	for _, s := range p {
+		l += math.Abs(s.length)
+	}
+	return l
+}
+
+// mod2pi maps angle to [0, 2pi)
+func mod2pi(t float64) float64 {
+	v := math.Mod(t, 2*math.Pi)
+	if v < 0 {
+		v += 2 * math.Pi
+	}
+	return v
+}
+
+// normAngle maps angle to [-pi, pi)
+func normAngle(t float64) float64 {
+	v := mod2pi(t + math.Pi)
+	return v - math.Pi
+}
+
+// moveOnCurve moves normalized pose (x,y,th) along one segment with length l
+func moveOnCurve(x, y, th float64, kind byte, l float64) (float64, float64, float64) {
+	switch kind {
+	case 'L':
+		nth := th + l
+		return x + math.Sin(nth) - math.Sin(th), y - math.Cos(nth) + math.Cos(th), nth
+	case 'R':
+		nth := th - l
+		return x - math.Sin(nth) + math.Sin(th), y + math.Cos(nth) - math.Cos(th), nth
+	}
+	return x + l*math.Cos(th), y + l*math.Sin(th), th
+}
+
+// reaches checks that the path really ends at (x,y,phi).
+// every candidate word is checked, so a degenerated formula never leaks out.
+func (p curvePath) reaches(x, y, phi float64) bool {
+	px, py, pth := 0.0, 0.0, 0.0
+	for _, s := range p {
+		px, py, pth = moveOnCurve(px, py, pth, s.kind, s.length)
+	}
+	return math.Hypot(px-x, py-y) < 1e-4 && math.Abs(normAngle(pth-phi)) < 1e-4
+}
+
+func polar(x, y float64) (float64, float64) {
+	return math.Hypot(x, y), math.Atan2(y, x)
+}
+
+// dubinsPaths returns the forward only words (LSL,RSR,LSR,RSL,RLR,LRL) to (x,y,phi)
+func dubinsPaths(x, y, phi float64) []curvePath {
+	d, theta := polar(x, y)
+	alpha := mod2pi(-theta)
+	beta := mod2pi(phi - theta)
+	sa, sb := math.Sin(alpha), math.Sin(beta)
+	ca, cb := math.Cos(alpha), math.Cos(beta)
+	cab := math.Cos(alpha - beta)
+
+	paths := make([]curvePath, 0, 6)
+	add := func(t, p, q float64, k0, k1, k2 byte) {
+		path := curvePath{{k0, t}, {k1, p}, {k2, q}}
+		if path.reaches(x, y, phi) {
+			paths = append(paths, path)
+		}
+	}
+
+	// LSL
+	if p2 := 2 + d*d - 2*cab + 2*d*(sa-sb); p2 >= 0 {
+		tmp := math.Atan2(cb-ca, d+sa-sb)
+		add(mod2pi(-alpha+tmp), math.Sqrt(p2), mod2pi(beta-tmp), 'L', 'S', 'L')
+	}
+	// RSR
+	if p2 := 2 + d*d - 2*cab + 2*d*(sb-sa); p2 >= 0 {
+		tmp := math.Atan2(ca-cb, d-sa+sb)
+		add(mod2pi(alpha-tmp), math.Sqrt(p2), mod2pi(-beta+tmp), 'R', 'S', 'R')
+	}
+	// LSR
+	if p2 := -2 + d*d + 2*cab + 2*d*(sa+sb); p2 >= 0 {
+		p := math.Sqrt(p2)
+		tmp := math.Atan2(-ca-cb, d+sa+sb) - math.Atan2(-2, p)
+		add(mod2pi(-alpha+tmp), p, mod2pi(-beta+tmp), 'L', 'S', 'R')
+	}
+	// RSL
+	if p2 := d*d - 2 + 2*cab - 2*d*(sa+sb); p2 >= 0 {
+		p := math.Sqrt(p2)
+		tmp := math.Atan2(ca+cb, d-sa-sb) - math.Atan2(2, p)
+		add(mod2pi(alpha-tmp), p, mod2pi(beta-tmp), 'R', 'S', 'L')
+	}
+	// RLR
+	if tmp := (6 - d*d + 2*cab + 2*d*(sa-sb)) / 8; math.Abs(tmp) <= 1 {
+		p := mod2pi(2*math.Pi - math.Acos(tmp))
+		t := mod2pi(alpha - math.Atan2(ca-cb, d-sa+sb) + p/2)
+		add(t, p, mod2pi(alpha-beta-t+p), 'R', 'L', 'R')
+	}
+	// LRL
+	if tmp := (6 - d*d + 2*cab + 2*d*(sb-sa)) / 8; math.Abs(tmp) <= 1 {
+		p := mod2pi(2*math.Pi - math.Acos(tmp))
+		t := mod2pi(-alpha - math.Atan2(ca-cb, d+sa-sb) + p/2)
+		add(t, p, mod2pi(beta-alpha-t+p), 'L', 'R', 'L')
+	}
+	return paths
+}
+
+// Reeds-Shepp base words, (t,u,v) of the Reeds-Shepp paper (formula 8.1 - 8.3)
+func lpSpLp(x, y, phi float64) (float64, float64, float64, bool) {
+	u, t := polar(x-math.Sin(phi), y-1+math.Cos(phi))
+	if t < -curveEps {
+		return 0, 0, 0, false
+	}
+	v := normAngle(phi - t)
+	return t, u, v, v >= -curveEps
+}
+
+func lpSpRp(x, y, phi float64) (float64, float64, float64, bool) {
+	u1, t1 := polar(x+math.Sin(phi), y-1-math.Cos(phi))
+	u1 = u1 * u1
+	if u1 < 4 {
+		return 0, 0, 0, false
+	}
+	u := math.Sqrt(u1 - 4)
+	t := normAngle(t1 + math.Atan2(2, u))
+	v := normAngle(t - phi)
+	return t, u, v, t >= -curveEps && v >= -curveEps
+}
+
+func lpRmL(x, y, phi float64) (float64, float64, float64, bool) {
+	u1, theta := polar(x-math.Sin(phi), y-1+math.Cos(phi))
+	if u1 > 4 {
+		return 0, 0, 0, false
+	}
+	u := -2 * math.Asin(0.25*u1)
+	t := normAngle(theta + 0.5*u + math.Pi)
+	v := normAngle(phi - t + u)
+	return t, u, v, t >= -curveEps && u <= curveEps
+}
+
+// tauOmega is the helper of the CCCC words (Reeds-Shepp paper, 8.7)
+func tauOmega(u, v, xi, eta, phi float64) (float64, float64) {
+	delta := normAngle(u - v)
+	a := math.Sin(u) - math.Sin(delta)
+	b := math.Cos(u) - math.Cos(delta) - 1
+	t1 := math.Atan2(eta*a-xi*b, xi*a+eta*b)
+	t2 := 2*(math.Cos(delta)-math.Cos(v)-math.Cos(u)) + 3
+	tau := normAngle(t1)
+	if t2 < 0 {
+		tau = normAngle(t1 + math.Pi)
+	}
+	return tau, normAngle(tau - u + v - phi)
+}
+
+// formula 8.7
+func lpRupLumRm(x, y, phi float64) (float64, float64, float64, bool) {
+	xi, eta := x+math.Sin(phi), y-1-math.Cos(phi)
+	rho := 0.25 * (2 + math.Hypot(xi, eta))
+	if rho > 1 {
+		return 0, 0, 0, false
+	}
+	u := math.Acos(rho)
+	t, v := tauOmega(u, -u, xi, eta, phi)
+	return t, u, v, t >= -curveEps && v <= curveEps
+}
+
+// formula 8.8
+func lpRumLumRp(x, y, phi float64) (float64, float64, float64, bool) {
+	xi, eta := x+math.Sin(phi), y-1-math.Cos(phi)
+	rho := (20 - xi*xi - eta*eta) / 16
+	if rho < 0 || rho > 1 {
+		return 0, 0, 0, false
+	}
+	u := -math.Acos(rho)
+	if u < -0.5*math.Pi {
+		return 0, 0, 0, false
+	}
+	t, v := tauOmega(u, u, xi, eta, phi)
+	return t, u, v, t >= -curveEps && v >= -curveEps
+}
+
+// formula 8.9
+func lpRmSmLm(x, y, phi float64) (float64, float64, float64, bool) {
+	rho, theta := polar(x-math.Sin(phi), y-1+math.Cos(phi))
+	if rho < 2 {
+		return 0, 0, 0, false
+	}
+	r := math.Sqrt(rho*rho - 4)
+	u := 2 - r
+	t := normAngle(theta + math.Atan2(r, -2))
+	v := normAngle(phi - 0.5*math.Pi - t)
+	return t, u, v, t >= -curveEps && u <= curveEps && v <= curveEps
+}
+
+// formula 8.10
+func lpRmSmRm(x, y, phi float64) (float64, float64, float64, bool) {
+	xi, eta := x+math.Sin(phi), y-1-math.Cos(phi)
+	rho, theta := polar(-eta, xi)
+	if rho < 2 {
+		return 0, 0, 0, false
+	}
+	t := theta
+	u := 2 - rho
+	v := normAngle(t + 0.5*math.Pi - phi)
+	return t, u, v, t >= -curveEps && u <= curveEps && v <= curveEps
+}
+
+// formula 8.11 (with the correction of the typo in the paper)
+func lpRmSLmRp(x, y, phi float64) (float64, float64, float64, bool) {
+	xi, eta := x+math.Sin(phi), y-1-math.Cos(phi)
+	rho, _ := polar(xi, eta)
+	if rho < 2 {
+		return 0, 0, 0, false
+	}
+	u := 4 - math.Sqrt(rho*rho-4)
+	if u > curveEps {
+		return 0, 0, 0, false
+	}
+	t := normAngle(math.Atan2((4-u)*xi-2*eta, -2*xi+(u-4)*eta))
+	v := normAngle(t - phi)
+	return t, u, v, t >= -curveEps && v >= -curveEps
+}
+
+// reedsSheppPaths returns the Reeds-Shepp words (CSC, CCC, CCCC, CCSC and CCSCC families
+// with time-flip and reflection) that reach (x,y,phi).
+func reedsSheppPaths(x, y, phi float64) []curvePath {
+	paths := make([]curvePath, 0, 48)
+	// word adds fn solved at (px,py,pphi) in the four symmetries (plain, timeflip, reflect, timeflip + reflect),
+	// kinds are the segments of the plain word, lengths maps (t,u,v) to the segment lengths.
+	word := func(fn func(x, y, phi float64) (float64, float64, float64, bool), px, py, pphi float64,
+		kinds string, lengths func(t, u, v float64) []float64) {
+		for _, s := range []struct {
+			x, y, phi, sign float64
+			reflect         bool
+		}{
+			{px, py, pphi, 1, false},
+			{-px, py, -pphi, -1, false},
+			{px, -py, -pphi, 1, true},
+			{-px, -py, pphi, -1, true},
+		} {
+			t, u, v, ok := fn(s.x, s.y, s.phi)
+			if !ok {
+				continue
+			}
+			ls := lengths(t, u, v)
+			path := make(curvePath, len(kinds))
+			for i := range path {
+				k := kinds[i]
+				if s.reflect {
+					k = reflectKind(k)
+				}
+				path[i] = curveSegment{k, s.sign * ls[i]}
+			}
+			if path.reaches(x, y, phi) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	tuv := func(t, u, v float64) []float64 { return []float64{t, u, v} }
+	vut := func(t, u, v float64) []float64 { return []float64{v, u, t} }
+	half := 0.5 * math.Pi
+	// backwards words are solved at the goal seen from the start in reverse
+	xb := x*math.Cos(phi) + y*math.Sin(phi)
+	yb := x*math.Sin(phi) - y*math.Cos(phi)
+
+	// CSC
+	word(lpSpLp, x, y, phi, "LSL", tuv)
+	word(lpSpRp, x, y, phi, "LSR", tuv)
+	// CCC
+	word(lpRmL, x, y, phi, "LRL", tuv)
+	word(lpRmL, xb, yb, phi, "LRL", vut)
+	// CCCC
+	word(lpRupLumRm, x, y, phi, "LRLR", func(t, u, v float64) []float64 { return []float64{t, u, -u, v} })
+	word(lpRumLumRp, x, y, phi, "LRLR", func(t, u, v float64) []float64 { return []float64{t, u, u, v} })
+	// CCSC
+	word(lpRmSmLm, x, y, phi, "LRSL", func(t, u, v float64) []float64 { return []float64{t, -half, u, v} })
+	word(lpRmSmRm, x, y, phi, "LRSR", func(t, u, v float64) []float64 { return []float64{t, -half, u, v} })
+	word(lpRmSmLm, xb, yb, phi, "LSRL", func(t, u, v float64) []float64 { return []float64{v, u, -half, t} })
+	word(lpRmSmRm, xb, yb, phi, "RSRL", func(t, u, v float64) []float64 { return []float64{v, u, -half, t} })
+	// CCSCC
+	word(lpRmSLmRp, x, y, phi, "LRSLR", func(t, u, v float64) []float64 { return []float64{t, -half, u, -half, v} })
+	return paths
+}
+
+func reflectKind(k byte) byte {
+	switch k {
+	case 'L':
+		return 'R'
+	case 'R':
+		return 'L'
+	}
+	return k
+}
+
+// toCurveFrame converts goal pose into the normalized start frame.
+func toCurveFrame(start, goal Pose, radius float64) (float64, float64, float64) {
+	dx := goal.X - start.X
+	dy := goal.Y - start.Y
+	c, s := math.Cos(start.Theta), math.Sin(start.Theta)
+	return (c*dx + s*dy) / radius, (-s*dx + c*dy) / radius, goal.Theta - start.Theta
+}
+
+// shortestCurve returns the shortest analytic curve from start to goal.
+// if reverse is true, Reeds-Shepp words are used, otherwise Dubins words.
+func shortestCurve(start, goal Pose, radius float64, reverse bool) (curvePath, bool) {
+	x, y, phi := toCurveFrame(start, goal, radius)
+	var paths []curvePath
+	if reverse {
+		paths = reedsSheppPaths(x, y, phi)
+	} else {
+		paths = dubinsPaths(x, y, phi)
+	}
+	var best curvePath
+	for _, p := range paths {
+		if best == nil || p.length() < best.length() {
+			best = p
+		}
+	}
+	return best, best != nil
+}
+
+// sampleCurve samples poses along the curve every step (in cells).
+// first pose (start itself) is not included.
+func sampleCurve(start Pose, path curvePath, radius, step float64) []Pose {
+	poses := make([]Pose, 0)
+	x, y, th := 0.0, 0.0, 0.0
+	c, s := math.Cos(start.Theta), math.Sin(start.Theta)
+	for _, seg := range path {
+		segLen := math.Abs(seg.length) * radius
+		n := int(math.Ceil(segLen / step))
+		if n == 0 {
+			continue
+		}
+		dl := seg.length / float64(n)
+		for i := 0; i < n; i++ {
+			x, y, th = moveOnCurve(x, y, th, seg.kind, dl)
+			poses = append(poses, Pose{
+				X:       start.X + radius*(c*x-s*y),
+				Y:       start.Y + radius*(s*x+c*y),
+				Theta:   normAngle(start.Theta + th),
+				Reverse: seg.length < 0,
+			})
+		}
+	}
+	return poses
+}
@@ -0,0 +1,49 @@
+package astar_wr
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestReedsSheppAlwaysReaches(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	start := Pose{}
+	for i := 0; i < 5000; i++ {
+		goal := Pose{X: rnd.Float64()*20 - 10, Y: rnd.Float64()*20 - 10, Theta: rnd.Float64()*2*math.Pi - math.Pi}
+		x, y, phi := toCurveFrame(start, goal, 2)
+		paths := reedsSheppPaths(x, y, phi)
+		if len(paths) == 0 {
+			t.Fatalf("no Reeds-Shepp path to %+v", goal)
+		}
+		for _, p := range paths {
+			if !p.reaches(x, y, phi) {
+				t.Fatalf("path %v does not reach %+v", p, goal)
+			}
+		}
+		best, ok := shortestCurve(start, goal, 2, true)
+		if !ok {
+			t.Fatalf("shortestCurve to %+v failed", goal)
+		}
+		// Reeds-Shepp is never longer than Dubins
+		if d, ok := shortestCurve(start, goal, 2, false); ok && best.length() > d.length()+1e-6 {
+			t.Errorf("Reeds-Shepp %v is longer than Dubins %v to %+v", best, d, goal)
+		}
+	}
+}
+
+func TestSampleCurveEndsAtGoal(t *testing.T) {
+	start := Pose{X: 5, Y: 5, Theta: 0.3}
+	goal := Pose{X: 3, Y: 9, Theta: -2}
+	for _, reverse := range []bool{false, true} {
+		curve, ok := shortestCurve(start, goal, 2, reverse)
+		if !ok {
+			t.Fatalf("shortestCurve(reverse=%v) failed", reverse)
+		}
+		poses := sampleCurve(start, curve, 2, 0.5)
+		last := poses[len(poses)-1]
+		if math.Hypot(last.X-goal.X, last.Y-goal.Y) > 1e-6 || math.Abs(normAngle(last.Theta-goal.Theta)) > 1e-6 {
+			t.Errorf("reverse=%v: curve ends at %+v, want %+v", reverse, last, goal)
+		}
+	}
+}
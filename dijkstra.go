@@ -0,0 +1,187 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// One-to-many Dijkstra distance fields (same move cost as Plan)
+
+// DistanceField is the cost from sources to every cell (or from every cell to sources if Reverse).
+// index of Dist, Prev and Nearest is y*Width+x.
+type DistanceField struct {
+	Width, Height int
+	Sources       [][2]int
+	Reverse       bool
+	Dist          []float64 // Inf if not reachable
+	Prev          []int32   // next cell toward the source (-1: source or not reachable)
+	Nearest       []int16   // index of the nearest source (-1: not reachable)
+}
+
+// Dijkstra computes cost from (sx,sy) to every reachable cell
+func (a *Astar) Dijkstra(sx, sy int) (*DistanceField, error) {
+	return a.MultiDijkstra([][2]int{{sx, sy}}, false)
+}
+
+// MultiDijkstra computes cost from the nearest of sources (e.g. depots) to every cell.
+// if reverse is true, the cost from every cell to the nearest source is computed (one-way aware).
+func (a *Astar) MultiDijkstra(sources [][2]int, reverse bool) (*DistanceField, error) {
+	if len(sources) == 0 || len(sources) > math.MaxInt16 {
+		return nil, fmt.Errorf("wrong number of sources (%d)", len(sources))
+	}
+	for i, s := range sources {
+		if !a.verifyPoint(s[0], s[1]) {
+			return nil, fmt.Errorf("source %d (%d, %d) is not verified", i, s[0], s[1])
+		}
+	}
+	return a.dijkstra(sources, reverse), nil
+}
+
+func (a *Astar) dijkstra(sources [][2]int, reverse bool) *DistanceField {
+	n := a.Width * a.Height
+	f := &DistanceField{
+		Width:   a.Width,
+		Height:  a.Height,
+		Sources: sources,
+		Reverse: reverse,
+		Dist:    make([]float64, n),
+		Prev:    make([]int32, n),
+		Nearest: make([]int16, n),
+	}
+	for i := range f.Dist {
+		f.Dist[i] = math.Inf(1)
+		f.Prev[i] = -1
+		f.Nearest[i] = -1
+	}
+	pq := &priorityQueue{}
+	for i, s := range sources {
+		id := s[1]*a.Width + s[0]
+		if f.Dist[id] == 0 {
+			continue // same source
+		}
+		f.Dist[id] = 0
+		f.Nearest[id] = int16(i)
+		pq.push(id, 0)
+	}
+	for pq.Len() > 0 {
+		it := pq.pop()
+		if it.priority > f.Dist[it.key] {
+			continue
+		}
+		vx, vy := a.indToPosXY(it.key)
+		for k, m := range motion {
+			var ux, uy int
+			var c float64
+			var ok bool
+			if reverse { // move u -> v
+				ux, uy = vx-int(m[0]), vy-int(m[1])
+				if ux < 0 || uy < 0 || ux > a.MaxX || uy > a.MaxY {
+					continue
+				}
+				c, ok = a.moveCost(ux, uy, k)
+			} else { // move v -> u
+				ux, uy = vx+int(m[0]), vy+int(m[1])
+				if ux < 0 || uy < 0 || ux > a.MaxX || uy > a.MaxY {
+					continue
+				}
+				c, ok = a.moveCost(vx, vy, k)
+			}
+			if !ok {
+				continue
+			}
+			u := uy*a.Width + ux
+			if nd := it.priority + c; nd < f.Dist[u] {
+				f.Dist[u] = nd
+				f.Prev[u] = int32(it.key)
+				f.Nearest[u] = f.Nearest[it.key]
+				pq.push(u, nd)
+			}
+		}
+	}
+	return f
+}
+
+// Cost returns the cost of (x,y) (Inf if not reachable or out of map)
+func (f *DistanceField) Cost(x, y int) float64 {
+	if x < 0 || y < 0 || x >= f.Width || y >= f.Height {
+		return math.Inf(1)
+	}
+	return f.Dist[y*f.Width+x]
+}
+
+// NearestSource returns index of the nearest source of (x,y) (-1: not reachable)
+func (f *DistanceField) NearestSource(x, y int) int {
+	if math.IsInf(f.Cost(x, y), 1) {
+		return -1
+	}
+	return int(f.Nearest[y*f.Width+x])
+}
+
+// Route extracts the route between (x,y) and its nearest source in Plan format (goal first).
+// goal is (x,y) for forward field, the source for reverse field.
+func (f *DistanceField) Route(x, y int) ([][2]int, error) {
+	if math.IsInf(f.Cost(x, y), 1) {
+		return nil, fmt.Errorf("point (%d, %d) is not reachable", x, y)
+	}
+	var route [][2]int
+	for id := int32(y*f.Width + x); id != -1; id = f.Prev[id] {
+		route = append(route, [2]int{int(id) % f.Width, int(id) / f.Width})
+	}
+	if f.Reverse { // walked from start to goal
+		for i, j := 0, len(route)-1; i < j; i, j = i+1, j-1 {
+			route[i], route[j] = route[j], route[i]
+		}
+	}
+	return route, nil
+}
+
+// rectSearch is Dijkstra from cell src inside [x0,x1]x[y0,y1] (reverse: cost to src).
+// dist and link (previous cell, or next cell if reverse) are indexed by local index (y-y0)*(x1-x0+1)+x-x0.
+func (a *Astar) rectSearch(x0, y0, x1, y1, src int, reverse bool) (dist []float64, link []int32) {
+	w, h := x1-x0+1, y1-y0+1
+	dist = make([]float64, w*h)
+	link = make([]int32, w*h)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		link[i] = -1
+	}
+	sx, sy := a.indToPosXY(src)
+	dist[(sy-y0)*w+sx-x0] = 0
+	pq := &priorityQueue{}
+	pq.push((sy-y0)*w+sx-x0, 0)
+	for pq.Len() > 0 {
+		it := pq.pop()
+		if it.priority > dist[it.key] {
+			continue
+		}
+		cx, cy := it.key%w+x0, it.key/w+y0
+		for k, v := range motion {
+			var nx, ny int
+			var mc float64
+			var ok bool
+			if reverse {
+				nx, ny = cx-int(v[0]), cy-int(v[1])
+				if nx < x0 || nx > x1 || ny < y0 || ny > y1 {
+					continue
+				}
+				mc, ok = a.moveCost(nx, ny, k)
+			} else {
+				nx, ny = cx+int(v[0]), cy+int(v[1])
+				if nx < x0 || nx > x1 || ny < y0 || ny > y1 {
+					continue
+				}
+				mc, ok = a.moveCost(cx, cy, k)
+			}
+			if !ok {
+				continue
+			}
+			n := (ny-y0)*w + nx - x0
+			if nd := it.priority + mc; nd < dist[n] {
+				dist[n] = nd
+				link[n] = int32(it.key)
+				pq.push(n, nd)
+			}
+		}
+	}
+	return dist, link
+}
@@ -0,0 +1,146 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Directional (one-way) lanes
+// each cell has bitmask of motion index (see motion) for forbidden and penalized moves.
+
+// DirectionMap keeps traffic rules of cells, set to Astar.Directions
+type DirectionMap struct {
+	Forbidden [][]uint8 // moves not allowed in/out of the cell
+	Penalized [][]uint8 // moves with Penalty
+	Penalty   [][]float64
+}
+
+// NewDirectionMap returns empty (isotropic) DirectionMap for the Astar
+func NewDirectionMap(a *Astar) *DirectionMap {
+	d := &DirectionMap{
+		Forbidden: make([][]uint8, a.Width),
+		Penalized: make([][]uint8, a.Width),
+		Penalty:   make([][]float64, a.Width),
+	}
+	for x := 0; x < a.Width; x++ {
+		d.Forbidden[x] = make([]uint8, a.Height)
+		d.Penalized[x] = make([]uint8, a.Height)
+		d.Penalty[x] = make([]float64, a.Height)
+	}
+	return d
+}
+
+// againstMask returns bitmask of motions which go against heading (radian, image coordinate)
+func againstMask(heading float64) uint8 {
+	hx, hy := math.Cos(heading), math.Sin(heading)
+	var mask uint8
+	for k, v := range motion {
+		if (hx*v[0]+hy*v[1])/v[2] < -0.1 {
+			mask |= 1 << uint(k)
+		}
+	}
+	return mask
+}
+
+// SetOneWay sets one-way rule of the cell with travel heading (radian).
+// penalty <= 0 forbids reverse travel, otherwise penalty is added to reverse moves.
+func (d *DirectionMap) SetOneWay(x, y int, heading float64, penalty float64) {
+	if x < 0 || y < 0 || x >= len(d.Forbidden) || y >= len(d.Forbidden[x]) {
+		return
+	}
+	mask := againstMask(heading)
+	if penalty <= 0 {
+		d.Forbidden[x][y] |= mask
+	} else {
+		d.Penalized[x][y] |= mask
+		if penalty > d.Penalty[x][y] {
+			d.Penalty[x][y] = penalty
+		}
+	}
+}
+
+// Clear removes rules of the cell
+func (d *DirectionMap) Clear(x, y int) {
+	if x < 0 || y < 0 || x >= len(d.Forbidden) || y >= len(d.Forbidden[x]) {
+		return
+	}
+	d.Forbidden[x][y] = 0
+	d.Penalized[x][y] = 0
+	d.Penalty[x][y] = 0
+}
+
+// moveCost returns additional cost of motion k between (x,y) and (nx,ny), ok is false if forbidden.
+func (d *DirectionMap) moveCost(x, y, nx, ny, k int) (float64, bool) {
+	bit := uint8(1) << uint(k)
+	if d.Forbidden[x][y]&bit != 0 || d.Forbidden[nx][ny]&bit != 0 {
+		return 0, false
+	}
+	cost := 0.0
+	if d.Penalized[x][y]&bit != 0 {
+		cost = d.Penalty[x][y]
+	}
+	if d.Penalized[nx][ny]&bit != 0 && d.Penalty[nx][ny] > cost {
+		cost = d.Penalty[nx][ny]
+	}
+	return cost, true
+}
+
+// moveCost returns cost to move from (x,y) to neighbor with motion[k].
+// ok is false if the move is not allowed (obstacle or traffic rule).
+func (a *Astar) moveCost(x, y, k int) (float64, bool) {
+	v := motion[k]
+	nx := x + int(v[0])
+	ny := y + int(v[1])
+	if a.CostMap[nx][ny] == 0xff {
+		return 0, false
+	}
+	cost := v[2] + float64(a.CostMap[nx][ny])
+	if a.Directions != nil {
+		dc, ok := a.Directions.moveCost(x, y, nx, ny, k)
+		if !ok {
+			return 0, false
+		}
+		cost += dc
+	}
+	return cost, true
+}
+
+// motionIndex returns motion index nearest to heading (radian)
+func motionIndex(heading float64) int {
+	best, bk := -2.0, 0
+	hx, hy := math.Cos(heading), math.Sin(heading)
+	for k, v := range motion {
+		if dot := (hx*v[0] + hy*v[1]) / v[2]; dot > best {
+			best, bk = dot, k
+		}
+	}
+	return bk
+}
+
+// ApplyDirections rasterizes one-way zones into Astar.Directions (created if nil).
+// zone heading is used for polygon, line zone uses direction of each segment.
+func (zs *ZoneSet) ApplyDirections(a *Astar) error {
+	if a.Directions == nil {
+		a.Directions = NewDirectionMap(a)
+	}
+	for _, z := range zs.Zones {
+		if z.Kind != ZoneOneWay {
+			continue
+		}
+		if z.Width > 0 {
+			for i := 1; i < len(z.Polygon); i++ {
+				p0, p1 := z.Polygon[i-1], z.Polygon[i]
+				heading := math.Atan2(p1[1]-p0[1], p1[0]-p0[0])
+				seg := Zone{Width: z.Width, Polygon: [][2]float64{p0, p1}}
+				seg.Cells(a, func(x, y int) { a.Directions.SetOneWay(x, y, heading, float64(z.Cost)) })
+			}
+		} else {
+			if z.Heading == nil {
+				return fmt.Errorf("zone %s: oneway polygon needs heading", z.Name)
+			}
+			heading := *z.Heading * math.Pi / 180
+			z.Cells(a, func(x, y int) { a.Directions.SetOneWay(x, y, heading, float64(z.Cost)) })
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package astar_wr
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// laneMap is 20x9 map with two lanes (y<4, y>4) divided by a wall open at both ends
+func laneMap() *Astar {
+	var objects [][2]int
+	for x := 2; x < 18; x++ {
+		objects = append(objects, [2]int{x, 4})
+	}
+	return NewWeightedAstar(objects, 20, 9, 0)
+}
+
+// straightRoute returns cells from (x0,y) to (x1,y) in Plan format
+func straightRoute(x0, x1, y int) [][2]int {
+	var route [][2]int
+	for x := x1; x != x0; x -= sign(x1 - x0) {
+		route = append(route, [2]int{x, y})
+	}
+	return append(route, [2]int{x0, y})
+}
+
+func TestOneWayDetour(t *testing.T) {
+	a := laneMap()
+	d := NewDirectionMap(a)
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 4; y++ {
+			d.SetOneWay(x, y, 0, 0) // top lane goes east
+		}
+	}
+	a.Directions = d
+	back := straightRoute(15, 4, 2)
+	if _, blocked := a.RouteCost(back); blocked < 0 {
+		t.Error("wrong way route is accepted")
+	}
+	if _, blocked := a.RouteCost(straightRoute(4, 15, 2)); blocked >= 0 {
+		t.Errorf("route along the lane is blocked at %d", blocked)
+	}
+	route, err := a.Plan(15, 2, 4, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, blocked := a.RouteCost(route)
+	if blocked >= 0 {
+		t.Fatalf("Plan route is blocked at %d", blocked)
+	}
+	detour := false
+	for _, p := range route {
+		detour = detour || p[1] > 4
+	}
+	if !detour || cost <= 11 {
+		t.Errorf("route does not take the other lane (cost %f)", cost)
+	}
+}
+
+func TestOneWayPenalty(t *testing.T) {
+	a := laneMap()
+	zs, err := LoadZones(strings.NewReader(`{"zones": [{"name": "top", "kind": "oneway", "cost": 1, "width": 4, "polygon": [[0,1.5],[19,1.5]]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zs.ApplyDirections(a); err != nil {
+		t.Fatal(err)
+	}
+	// reverse moves cost 1 more, still cheaper than the detour
+	back := straightRoute(15, 4, 2)
+	cost, blocked := a.RouteCost(back)
+	if blocked >= 0 || math.Abs(cost-11*2) > 1e-9 {
+		t.Errorf("wrong way cost %f (blocked %d), want %d", cost, blocked, 11*2)
+	}
+	route, err := a.Plan(15, 2, 4, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := a.RouteCost(route); math.Abs(c-cost) > 1e-9 {
+		t.Errorf("Plan cost %f, want %f", c, cost)
+	}
+	zs.Zones[0].Width = 0 // polygon without heading
+	if err := zs.ApplyDirections(a); err == nil {
+		t.Error("oneway polygon without heading is accepted")
+	}
+}
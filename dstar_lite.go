@@ -0,0 +1,253 @@
+package astar_wr
+
+import (
+	"container/heap"
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// D* Lite incremental replanning (Koenig & Likhachev 2002)
+// search is from goal to start, so the tree is kept while the robot moves.
+
+type dstarKey [2]float64
+
+// less compares keys, first elements are compared with tolerance of rounding errors
+func (k dstarKey) less(o dstarKey) bool {
+	const eps = 1e-9
+	return k[0] < o[0]-eps || (math.Abs(k[0]-o[0]) <= eps && k[1] < o[1])
+}
+
+type dstarItem struct {
+	key   dstarKey
+	index int
+}
+
+type dstarQueue []dstarItem
+
+func (q dstarQueue) Len() int            { return len(q) }
+func (q dstarQueue) Less(i, j int) bool  { return q[i].key.less(q[j].key) }
+func (q dstarQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dstarQueue) Push(x interface{}) { *q = append(*q, x.(dstarItem)) }
+func (q *dstarQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	*q = old[:n-1]
+	return it
+}
+
+// DStarLite keeps search tree between plans, notify cost changes by UpdateCell / CellsChanged.
+type DStarLite struct {
+	Astar  *Astar
+	Weight float64 // heuristic weight (1.0 or less keeps route optimal)
+
+	g     []float64
+	rhs   []float64
+	km    float64
+	start int
+	last  int
+	goal  int
+
+	queue   dstarQueue
+	openKey map[int]dstarKey // current key of cells in queue (lazy deletion)
+}
+
+// NewDStarLite prepares D* Lite from (sx,sy) to (gx,gy)
+func NewDStarLite(a *Astar, sx, sy, gx, gy int, weight float64) (*DStarLite, error) {
+	if !a.verifyPoint(sx, sy) {
+		return nil, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	d := &DStarLite{
+		Astar:   a,
+		Weight:  weight,
+		g:       make([]float64, a.Width*a.Height),
+		rhs:     make([]float64, a.Width*a.Height),
+		start:   sy*a.Width + sx,
+		goal:    gy*a.Width + gx,
+		openKey: make(map[int]dstarKey),
+	}
+	for i := range d.g {
+		d.g[i] = math.Inf(1)
+		d.rhs[i] = math.Inf(1)
+	}
+	d.last = d.start
+	d.rhs[d.goal] = 0
+	d.insert(d.goal, d.calcKey(d.goal))
+	return d, nil
+}
+
+func (d *DStarLite) h(i, j int) float64 {
+	ix, iy := d.Astar.indToPosXY(i)
+	jx, jy := d.Astar.indToPosXY(j)
+	return d.Weight * math.Hypot(float64(ix-jx), float64(iy-jy))
+}
+
+func (d *DStarLite) calcKey(s int) dstarKey {
+	m := math.Min(d.g[s], d.rhs[s])
+	return dstarKey{m + d.h(d.start, s) + d.km, m}
+}
+
+func (d *DStarLite) insert(s int, k dstarKey) {
+	d.openKey[s] = k
+	heap.Push(&d.queue, dstarItem{key: k, index: s})
+}
+
+// top removes stale entries and returns the top item
+func (d *DStarLite) top() (dstarItem, bool) {
+	for d.queue.Len() > 0 {
+		it := d.queue[0]
+		if k, ok := d.openKey[it.index]; ok && k == it.key {
+			return it, true
+		}
+		heap.Pop(&d.queue)
+	}
+	return dstarItem{}, false
+}
+
+// cost returns cost of the edge from u to v (v is neighbor with motion k)
+func (d *DStarLite) cost(u, k int) (int, float64) {
+	a := d.Astar
+	x, y := a.indToPosXY(u)
+	nx := x + int(motion[k][0])
+	ny := y + int(motion[k][1])
+	if nx < 0 || ny < 0 || nx > a.MaxX || ny > a.MaxY {
+		return -1, math.Inf(1)
+	}
+	c, ok := a.moveCost(x, y, k)
+	if !ok {
+		return ny*a.Width + nx, math.Inf(1)
+	}
+	return ny*a.Width + nx, c
+}
+
+func (d *DStarLite) updateVertex(u int) {
+	if u != d.goal {
+		min := math.Inf(1)
+		for k := range motion {
+			if v, c := d.cost(u, k); v >= 0 && c+d.g[v] < min {
+				min = c + d.g[v]
+			}
+		}
+		d.rhs[u] = min
+	}
+	delete(d.openKey, u)
+	if d.g[u] != d.rhs[u] {
+		d.insert(u, d.calcKey(u))
+	}
+}
+
+// updateAround updates the cell and its neighbors (predecessors of the cell)
+func (d *DStarLite) updateAround(u int) {
+	a := d.Astar
+	x, y := a.indToPosXY(u)
+	for _, v := range motion {
+		nx := x + int(v[0])
+		ny := y + int(v[1])
+		if nx < 0 || ny < 0 || nx > a.MaxX || ny > a.MaxY {
+			continue
+		}
+		d.updateVertex(ny*a.Width + nx)
+	}
+	d.updateVertex(u)
+}
+
+func (d *DStarLite) computeShortestPath() {
+	a := d.Astar
+	for {
+		it, ok := d.top()
+		if !ok || (!it.key.less(d.calcKey(d.start)) && d.rhs[d.start] == d.g[d.start]) {
+			return
+		}
+		u := it.index
+		heap.Pop(&d.queue)
+		delete(d.openKey, u)
+		if a.UpdateObj != nil {
+			ux, uy := a.indToPosXY(u)
+			a.Current = newNode(ux, uy, d.g[u], -1)
+			a.UpdateObj.UpdateAstar(a, color.RGBA{0xa0, 0xb0, 0xb0, 0xff}, 0)
+		}
+		if nk := d.calcKey(u); it.key.less(nk) {
+			d.insert(u, nk)
+		} else if d.g[u] > d.rhs[u] {
+			d.g[u] = d.rhs[u]
+			d.updateAround(u)
+		} else {
+			d.g[u] = math.Inf(1)
+			d.updateAround(u)
+		}
+	}
+}
+
+// Plan (re)computes route from current start to goal (same format as Astar.Plan).
+func (d *DStarLite) Plan() (route [][2]int, err error) {
+	a := d.Astar
+	d.computeShortestPath()
+	sx, sy := a.indToPosXY(d.start)
+	gx, gy := a.indToPosXY(d.goal)
+	if math.IsInf(d.g[d.start], 1) {
+		return route, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): no route", sx, sy, gx, gy)
+	}
+	path := [][2]int{{sx, sy}}
+	for u := d.start; u != d.goal; {
+		next, min := -1, math.Inf(1)
+		for k := range motion {
+			if v, c := d.cost(u, k); v >= 0 && c+d.g[v] < min {
+				next, min = v, c+d.g[v]
+			}
+		}
+		if next < 0 || len(path) > a.Width*a.Height {
+			return route, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): broken tree", sx, sy, gx, gy)
+		}
+		u = next
+		nx, ny := a.indToPosXY(u)
+		path = append(path, [2]int{nx, ny})
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		route = append(route, path[i])
+	}
+	return route, a.checkUnknown(route)
+}
+
+// Cost returns cost of the current route from start (Inf if not planned or no route)
+func (d *DStarLite) Cost() float64 {
+	return d.g[d.start]
+}
+
+// Move sets new start (robot position) keeping the search tree
+func (d *DStarLite) Move(x, y int) error {
+	if x < 0 || y < 0 || x > d.Astar.MaxX || y > d.Astar.MaxY {
+		return fmt.Errorf("point (%d, %d) is out of map", x, y)
+	}
+	d.start = y*d.Astar.Width + x
+	d.km += d.h(d.last, d.start)
+	d.last = d.start
+	return nil
+}
+
+// UpdateCell sets cost of the cell by Astar.SetCost and repairs the tree
+// (Version is incremented and other listeners are also notified).
+func (d *DStarLite) UpdateCell(x, y int, cost byte) {
+	a := d.Astar
+	if x < 0 || y < 0 || x > a.MaxX || y > a.MaxY {
+		return
+	}
+	if !a.isListener(d) { // the tree is repaired by MapChanged
+		a.AddListener(d)
+		defer a.RemoveListener(d)
+	}
+	a.SetCost(x, y, cost)
+}
+
+// CellsChanged notifies cells whose cost (CostMap or Directions) was changed
+func (d *DStarLite) CellsChanged(cells [][2]int) {
+	for _, c := range cells {
+		if c[0] < 0 || c[1] < 0 || c[0] > d.Astar.MaxX || c[1] > d.Astar.MaxY {
+			continue
+		}
+		d.updateAround(c[1]*d.Astar.Width + c[0])
+	}
+}
@@ -0,0 +1,50 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func checkDStarCost(t *testing.T, d *DStarLite, sx, sy, gx, gy int) {
+	t.Helper()
+	route, err := d.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := d.Astar.Dijkstra(sx, sy)
+	want := f.Cost(gx, gy)
+	if math.Abs(d.Cost()-want) > 1e-9 {
+		t.Errorf("cost %f from (%d,%d), Dijkstra %f", d.Cost(), sx, sy, want)
+	}
+	if c, blocked := d.Astar.RouteCost(route); blocked >= 0 || math.Abs(c-want) > 1e-9 {
+		t.Errorf("route cost %f (blocked %d), Dijkstra %f", c, blocked, want)
+	}
+}
+
+func TestDStarLiteMatchesDijkstra(t *testing.T) {
+	var objects [][2]int
+	for y := 0; y < 25; y++ {
+		objects = append(objects, [2]int{15, y})
+	}
+	a := NewWeightedAstar(objects, 30, 30, 2)
+	d, err := NewDStarLite(a, 2, 2, 27, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDStarCost(t, d, 2, 2, 27, 3)
+
+	// close the opening below the wall
+	for y := 25; y < 28; y++ {
+		d.UpdateCell(15, y, 0xff)
+	}
+	checkDStarCost(t, d, 2, 2, 27, 3)
+
+	// the robot moves and the wall is opened at the top
+	if err := d.Move(5, 10); err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 4; y++ {
+		d.UpdateCell(15, y, 0)
+	}
+	checkDStarCost(t, d, 5, 10, 27, 3)
+}
@@ -0,0 +1,122 @@
+package astar_wr
+
+import "math"
+
+// Goal regions: plan to the cheapest reachable cell of an area (e.g. face of a shelf)
+
+// GoalRegion is a set of goal cells with lower bound of Euclid distance to it
+// (admissible heuristic, 0 is always allowed).
+type GoalRegion interface {
+	Contains(x, y int) bool
+	Distance(x, y int) float64
+}
+
+// GoalSet is a region of listed cells
+type GoalSet struct {
+	cells          map[[2]int]bool
+	list           [][2]int
+	x0, y0, x1, y1 int // bounding box
+}
+
+// NewGoalSet makes goal region from cells
+func NewGoalSet(cells [][2]int) *GoalSet {
+	g := &GoalSet{cells: make(map[[2]int]bool), list: cells}
+	if len(cells) > 0 {
+		g.x0, g.y0, g.x1, g.y1 = bbox(cells)
+	}
+	for _, c := range cells {
+		g.cells[c] = true
+	}
+	return g
+}
+
+func (g *GoalSet) Contains(x, y int) bool {
+	return g.cells[[2]int{x, y}]
+}
+
+// Distance is exact for small sets, distance to bounding box for large sets
+func (g *GoalSet) Distance(x, y int) float64 {
+	if len(g.list) > 64 {
+		return GoalRect{g.x0, g.y0, g.x1, g.y1}.Distance(x, y)
+	}
+	d := math.Inf(1)
+	for _, c := range g.list {
+		d = math.Min(d, math.Hypot(float64(x-c[0]), float64(y-c[1])))
+	}
+	return d
+}
+
+// GoalRect is every cell in [X0,X1]x[Y0,Y1]
+type GoalRect struct {
+	X0, Y0, X1, Y1 int
+}
+
+func (g GoalRect) Contains(x, y int) bool {
+	return x >= g.X0 && x <= g.X1 && y >= g.Y0 && y <= g.Y1
+}
+
+func (g GoalRect) Distance(x, y int) float64 {
+	dx := math.Max(0, math.Max(float64(g.X0-x), float64(x-g.X1)))
+	dy := math.Max(0, math.Max(float64(g.Y0-y), float64(y-g.Y1)))
+	return math.Hypot(dx, dy)
+}
+
+// GoalCircle is every cell within R from (X,Y)
+type GoalCircle struct {
+	X, Y int
+	R    float64
+}
+
+func (g GoalCircle) Contains(x, y int) bool {
+	return math.Hypot(float64(x-g.X), float64(y-g.Y)) <= g.R
+}
+
+func (g GoalCircle) Distance(x, y int) float64 {
+	return math.Max(0, math.Hypot(float64(x-g.X), float64(y-g.Y))-g.R)
+}
+
+// ShelfFace is the cells around shelf rectangle {x0,y0,x1,y1} within margin (outside of the shelf)
+type ShelfFace struct {
+	Shelf  [4]int
+	Margin int
+}
+
+func (g ShelfFace) outer() GoalRect {
+	return GoalRect{g.Shelf[0] - g.Margin, g.Shelf[1] - g.Margin, g.Shelf[2] + g.Margin, g.Shelf[3] + g.Margin}
+}
+
+func (g ShelfFace) Contains(x, y int) bool {
+	return g.outer().Contains(x, y) && !GoalRect{g.Shelf[0], g.Shelf[1], g.Shelf[2], g.Shelf[3]}.Contains(x, y)
+}
+
+func (g ShelfFace) Distance(x, y int) float64 {
+	return g.outer().Distance(x, y)
+}
+
+// GoalFunc is a goal predicate with optional heuristic (nil: 0, Dijkstra search)
+type GoalFunc struct {
+	Fn func(x, y int) bool
+	H  func(x, y int) float64
+}
+
+func (g GoalFunc) Contains(x, y int) bool {
+	return g.Fn(x, y)
+}
+
+func (g GoalFunc) Distance(x, y int) float64 {
+	if g.H == nil {
+		return 0
+	}
+	return g.H(x, y)
+}
+
+// PlanToRegion plans route from (sx,sy) to the cheapest reachable cell of the region (route[0]).
+// the route is optimal if weight <= 1 and Distance of the region is admissible.
+func (a *Astar) PlanToRegion(sx, sy int, g GoalRegion, weight float64) ([][2]int, error) {
+	return a.planGoal(sx, sy, g.Contains, func(x, y int) float64 { return weight * g.Distance(x, y) })
+}
+
+// PlanToSet plans route from (sx,sy) to the cheapest reachable cell of goals
+func (a *Astar) PlanToSet(sx, sy int, goals [][2]int, weight float64) ([][2]int, error) {
+	return a.PlanToRegion(sx, sy, NewGoalSet(goals), weight)
+}
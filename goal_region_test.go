@@ -0,0 +1,53 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlanToRegion(t *testing.T) {
+	a := shelfMap()
+	f, _ := a.Dijkstra(2, 20)
+	regions := map[string]GoalRegion{
+		"rect":   GoalRect{30, 10, 34, 14},
+		"circle": GoalCircle{45, 20, 3},
+		"face":   ShelfFace{Shelf: [4]int{24, 5, 25, 34}, Margin: 1},
+		"set":    NewGoalSet([][2]int{{50, 2}, {30, 37}, {12, 20}}),
+		"func":   GoalFunc{Fn: func(x, y int) bool { return x >= 40 }},
+	}
+	for name, g := range regions {
+		route, err := a.PlanToRegion(2, 20, g, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !g.Contains(route[0][0], route[0][1]) || route[len(route)-1] != [2]int{2, 20} {
+			t.Errorf("%s: route from %v to %v", name, route[len(route)-1], route[0])
+		}
+		// the route stops at the first cell in the region
+		for _, p := range route[1:] {
+			if g.Contains(p[0], p[1]) {
+				t.Errorf("%s: route passes %v in the region", name, p)
+			}
+		}
+		best := math.Inf(1)
+		for x := 0; x < a.Width; x++ {
+			for y := 0; y < a.Height; y++ {
+				if g.Contains(x, y) {
+					best = math.Min(best, f.Cost(x, y))
+				}
+			}
+		}
+		if c, _ := a.RouteCost(route); math.Abs(c-best) > 1e-9 {
+			t.Errorf("%s: cost %f, cheapest cell %f", name, c, best)
+		}
+	}
+	// region is not reachable
+	if _, err := a.PlanToRegion(2, 20, GoalRect{8, 10, 9, 12}, 1); err == nil {
+		t.Error("region in a shelf is reached")
+	}
+	// start in the region
+	route, err := a.PlanToSet(2, 20, [][2]int{{2, 20}, {5, 5}}, 1)
+	if err != nil || len(route) != 1 {
+		t.Errorf("start in the set: %v %v", route, err)
+	}
+}
@@ -0,0 +1,90 @@
+package astar_wr
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"math"
+)
+
+// GrayCostOption is the option of GrayCostAstar
+// gray value g is mapped to cost as below
+//
+//	g <  LethalThreth            : 0xff (obstacle, inflated with Iteration)
+//	g == Unknown                 : UnknownCost
+//	LethalThreth <= g < FreeThreth : MaxCost * ((FreeThreth - g)/(FreeThreth - LethalThreth))^Gamma
+//	g >= FreeThreth              : 0
+type GrayCostOption struct {
+	LethalThreth int     // same as closeThreth of ObjectMap
+	FreeThreth   int     // brighter than this is free
+	Unknown      int     // gray value of unknown cell (205 in ROS map), -1: not used
+	UnknownCost  byte    // cost of unknown cell
+	Gamma        float64 // curve of gray to cost (1: linear)
+	MaxCost      byte    // cost of the darkest passable gray (< 0xff)
+	Iteration    int     // obstacle inflation (same as WeightedAstar)
+}
+
+// DefaultGrayCostOption returns option for ROS style map (254 free, 205 unknown, 0 occupied)
+func DefaultGrayCostOption() GrayCostOption {
+	return GrayCostOption{
+		LethalThreth: 50,
+		FreeThreth:   250,
+		Unknown:      205,
+		UnknownCost:  0xfe,
+		Gamma:        1.0,
+		MaxCost:      200,
+		Iteration:    6,
+	}
+}
+
+// GrayCost returns traversal cost of gray value
+func (opt GrayCostOption) GrayCost(g int) byte {
+	switch {
+	case g < opt.LethalThreth:
+		return 0xff
+	case g == opt.Unknown:
+		return opt.UnknownCost
+	case g >= opt.FreeThreth:
+		return 0
+	}
+	r := float64(opt.FreeThreth-g) / float64(opt.FreeThreth-opt.LethalThreth)
+	c := math.Round(float64(opt.MaxCost) * math.Pow(r, opt.Gamma))
+	if c > 0xfe {
+		c = 0xfe
+	}
+	return byte(c)
+}
+
+// GrayCostAstar generates Astar from gray-scale image, gray values are used as traversal cost.
+// map size is same as the image, obstacles are inflated and combined by max.
+// unknown cells are marked in Astar.Unknown.
+func GrayCostAstar(imData image.Image, opt GrayCostOption) *Astar {
+	bound := imData.Bounds()
+	W := bound.Dx()
+	H := bound.Dy()
+	log.Printf("file loaded with %dx%d", W, H)
+
+	a := newAstar(W, H)
+	lethal := NewOccupancyGrid(W, H)
+	for x := 0; x < W; x++ {
+		for y := 0; y < H; y++ {
+			pixel := color.GrayModel.Convert(imData.At(bound.Min.X+x, bound.Min.Y+y)).(color.Gray).Y
+			c := opt.GrayCost(int(pixel))
+			a.CostMap[x][y] = c
+			if c == 0xff {
+				lethal.Set(x, y)
+			} else if int(pixel) == opt.Unknown {
+				if a.Unknown == nil {
+					a.Unknown = make([][]bool, W)
+					for i := range a.Unknown {
+						a.Unknown[i] = make([]bool, H)
+					}
+				}
+				a.Unknown[x][y] = true
+			}
+		}
+	}
+	a.inflate(lethal, opt.Iteration)
+	a.Iteration = opt.Iteration
+	return a
+}
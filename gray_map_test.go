@@ -0,0 +1,69 @@
+package astar_wr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGrayCost(t *testing.T) {
+	opt := DefaultGrayCostOption()
+	for _, c := range []struct {
+		g    int
+		want byte
+	}{
+		{0, 0xff}, {49, 0xff}, // darker than LethalThreth
+		{50, 200}, // MaxCost
+		{150, 100},
+		{205, 0xfe}, // unknown
+		{249, 1},
+		{250, 0}, {255, 0},
+	} {
+		if got := opt.GrayCost(c.g); got != c.want {
+			t.Errorf("GrayCost(%d) = %d, want %d", c.g, got, c.want)
+		}
+	}
+	opt.Gamma = 2
+	opt.Unknown = -1
+	if got := opt.GrayCost(150); got != 50 {
+		t.Errorf("GrayCost(150) with gamma 2 = %d, want 50", got)
+	}
+	if got := opt.GrayCost(205); got != 10 {
+		t.Errorf("GrayCost(205) without unknown = %d, want 10", got)
+	}
+}
+
+func TestGrayCostAstar(t *testing.T) {
+	im := image.NewGray(image.Rect(10, 20, 30, 30)) // 20x10, not at the origin
+	for x := 10; x < 30; x++ {
+		for y := 20; y < 30; y++ {
+			im.SetGray(x, y, color.Gray{Y: 254})
+		}
+	}
+	im.SetGray(10, 20, color.Gray{Y: 0})   // obstacle at (0,0)
+	im.SetGray(20, 25, color.Gray{Y: 150}) // gray at (10,5)
+	im.SetGray(25, 25, color.Gray{Y: 205}) // unknown at (15,5)
+	opt := DefaultGrayCostOption()
+	opt.Iteration = 2
+	a := GrayCostAstar(im, opt)
+	if a.Width != 20 || a.Height != 10 {
+		t.Fatalf("size %dx%d, want 20x10", a.Width, a.Height)
+	}
+	for _, c := range []struct {
+		x, y int
+		want byte
+	}{
+		{0, 0, 0xff},
+		{1, 0, inflationCost(1, 2)}, {0, 2, inflationCost(2, 2)}, {3, 0, 0},
+		{10, 5, 100},
+		{15, 5, 0xfe},
+		{19, 9, 0},
+	} {
+		if got := a.CostMap[c.x][c.y]; got != c.want {
+			t.Errorf("cost of (%d, %d) = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+	if a.Unknown == nil || !a.Unknown[15][5] || a.Unknown[10][5] {
+		t.Error("unknown cell is not marked")
+	}
+}
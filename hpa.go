@@ -0,0 +1,457 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Hierarchical path planning (HPA*, Botea et al. 2004)
+// the grid is partitioned into square clusters, entrances on cluster borders are abstract nodes.
+// intra-cluster edges keep their cell paths, so abstract routes are refined without search.
+
+// HPA is the abstract graph of the map. it can be a MapListener for local updates.
+type HPA struct {
+	Astar       *Astar
+	ClusterSize int
+	CW, CH      int // number of clusters in x, y
+
+	members map[int]map[int]bool // cluster -> entrance cells
+	edges   map[int][]hpaEdge    // entrance cell -> outgoing edges
+}
+
+type hpaEdge struct {
+	to    int
+	cost  float64
+	inter bool    // edge between clusters
+	path  []int32 // cells after the source to the destination (driving order)
+}
+
+// NewHPA builds the abstract graph with clusters of size x size cells
+func NewHPA(a *Astar, size int) *HPA {
+	if size < 2 {
+		size = 2
+	}
+	h := &HPA{
+		Astar:       a,
+		ClusterSize: size,
+		CW:          (a.Width + size - 1) / size,
+		CH:          (a.Height + size - 1) / size,
+		members:     make(map[int]map[int]bool),
+		edges:       make(map[int][]hpaEdge),
+	}
+	for c := 0; c < h.CW*h.CH; c++ {
+		h.members[c] = make(map[int]bool)
+	}
+	for cy := 0; cy < h.CH; cy++ {
+		for cx := 0; cx < h.CW; cx++ {
+			h.buildEntrances(cx, cy, true)
+			h.buildEntrances(cx, cy, false)
+		}
+	}
+	for c := 0; c < h.CW*h.CH; c++ {
+		h.buildIntra(c)
+	}
+	return h
+}
+
+// clusterOf returns cluster id of the cell
+func (h *HPA) clusterOf(x, y int) int {
+	return (y/h.ClusterSize)*h.CW + x/h.ClusterSize
+}
+
+// rect returns cells of the cluster
+func (h *HPA) rect(c int) (x0, y0, x1, y1 int) {
+	x0 = (c % h.CW) * h.ClusterSize
+	y0 = (c / h.CW) * h.ClusterSize
+	x1 = x0 + h.ClusterSize - 1
+	y1 = y0 + h.ClusterSize - 1
+	if x1 > h.Astar.MaxX {
+		x1 = h.Astar.MaxX
+	}
+	if y1 > h.Astar.MaxY {
+		y1 = h.Astar.MaxY
+	}
+	return
+}
+
+// border returns the cells on both sides of the right (or bottom) border of cluster (cx,cy)
+func (h *HPA) border(cx, cy int, right bool) (p, q [][2]int) {
+	x0, y0, x1, y1 := h.rect(cy*h.CW + cx)
+	if right {
+		if cx+1 >= h.CW {
+			return nil, nil
+		}
+		for y := y0; y <= y1; y++ {
+			p = append(p, [2]int{x1, y})
+			q = append(q, [2]int{x1 + 1, y})
+		}
+	} else {
+		if cy+1 >= h.CH {
+			return nil, nil
+		}
+		for x := x0; x <= x1; x++ {
+			p = append(p, [2]int{x, y1})
+			q = append(q, [2]int{x, y1 + 1})
+		}
+	}
+	return p, q
+}
+
+// entrances returns entrance cells of cluster c in order (deterministic edges and tie-breaking)
+func (h *HPA) entrances(c int) []int {
+	ids := make([]int, 0, len(h.members[c]))
+	for id := range h.members[c] {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (h *HPA) addNode(x, y int) int {
+	id := y*h.Astar.Width + x
+	h.members[h.clusterOf(x, y)][id] = true
+	return id
+}
+
+// crossing returns true if move between cells u and v crosses the right (or bottom) border of cluster (cx,cy).
+// diagonal moves over a corner of clusters belong to the right border.
+func (h *HPA) crossing(cx, cy int, right bool, u, v [2]int) bool {
+	x0, y0, x1, y1 := h.rect(cy*h.CW + cx)
+	if u[0] > v[0] || u[0] == v[0] && u[1] > v[1] {
+		u, v = v, u
+	}
+	if right {
+		return u[0] == x1 && v[0] == x1+1 && u[1] >= y0 && u[1] <= y1
+	}
+	if u[1] > v[1] {
+		u, v = v, u
+	}
+	return u[1] == y1 && v[1] == y1+1 && u[0] >= x0 && u[0] <= x1 && v[0] >= x0 && v[0] <= x1
+}
+
+// passable is true if move u->v or v->u is allowed
+func (h *HPA) passable(u, v [2]int) bool {
+	a := h.Astar
+	if !a.verifyPoint(u[0], u[1]) || !a.verifyPoint(v[0], v[1]) {
+		return false
+	}
+	_, ok1 := a.moveCost(u[0], u[1], motionOf(v[0]-u[0], v[1]-u[1]))
+	_, ok2 := a.moveCost(v[0], v[1], motionOf(u[0]-v[0], u[1]-v[1]))
+	return ok1 || ok2
+}
+
+// transition adds entrances u, v and the allowed moves between them
+func (h *HPA) transition(u, v [2]int) {
+	a := h.Astar
+	iu, iv := h.addNode(u[0], u[1]), h.addNode(v[0], v[1])
+	if c, ok := a.moveCost(u[0], u[1], motionOf(v[0]-u[0], v[1]-u[1])); ok {
+		h.edges[iu] = append(h.edges[iu], hpaEdge{to: iv, cost: c, inter: true, path: []int32{int32(iv)}})
+	}
+	if c, ok := a.moveCost(v[0], v[1], motionOf(u[0]-v[0], u[1]-v[1])); ok {
+		h.edges[iv] = append(h.edges[iv], hpaEdge{to: iu, cost: c, inter: true, path: []int32{int32(iu)}})
+	}
+}
+
+// buildEntrances makes transitions on the right (or bottom) border of cluster (cx,cy).
+// short openings have one transition in the middle, long openings have both ends and the middle.
+// diagonal moves across the border get transitions unless both sides of them are open.
+func (h *HPA) buildEntrances(cx, cy int, right bool) {
+	a := h.Astar
+	p, q := h.border(cx, cy, right)
+	open := func(i int) bool { return h.passable(p[i], q[i]) }
+	for i := 0; i < len(p); {
+		if !open(i) {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(p) && open(j+1) {
+			j++
+		}
+		if j-i+1 >= 6 {
+			h.transition(p[i], q[i])
+			h.transition(p[(i+j)/2], q[(i+j)/2])
+			h.transition(p[j], q[j])
+		} else {
+			h.transition(p[(i+j)/2], q[(i+j)/2])
+		}
+		i = j + 1
+	}
+	// along: step along the border
+	along := [2]int{1, 0}
+	if right {
+		along = [2]int{0, 1}
+	}
+	for i := range p {
+		for _, s := range []int{-1, 1} {
+			d := [2]int{q[i][0] + s*along[0], q[i][1] + s*along[1]}
+			if d[0] > a.MaxX || d[1] > a.MaxY || d[0] < 0 || d[1] < 0 || !h.crossing(cx, cy, right, p[i], d) {
+				continue
+			}
+			pd := [2]int{p[i][0] + s*along[0], p[i][1] + s*along[1]}
+			if h.passable(p[i], d) && !(h.passable(p[i], q[i]) && h.passable(pd, d)) {
+				h.transition(p[i], d)
+			}
+		}
+	}
+}
+
+// clusterSearch is Dijkstra from cell src inside cluster c (reverse: cost to src).
+// dist and link are indexed by local cell index of the cluster rect.
+func (h *HPA) clusterSearch(c, src int, reverse bool) (dist []float64, link []int32, x0, y0, w int) {
+	x0, y0, x1, y1 := h.rect(c)
+	dist, link = h.Astar.rectSearch(x0, y0, x1, y1, src, reverse)
+	return dist, link, x0, y0, x1 - x0 + 1
+}
+
+// clusterEdge makes edge from src to dst with the search result of src (forward) or dst (reverse)
+func (h *HPA) clusterEdge(src, dst int, dist []float64, link []int32, x0, y0, w int, reverse bool) (hpaEdge, bool) {
+	width := h.Astar.Width
+	local := func(id int) int32 { return int32((id/width-y0)*w + id%width - x0) }
+	global := func(l int32) int32 { return int32((int(l)/w+y0)*width + int(l)%w + x0) }
+	far := dst // the end searched from the other side
+	if reverse {
+		far = src
+	}
+	l := local(far)
+	if math.IsInf(dist[l], 1) {
+		return hpaEdge{}, false
+	}
+	e := hpaEdge{to: dst, cost: dist[l]}
+	if reverse { // link is next cell toward dst
+		for i := link[l]; i != -1; i = link[i] {
+			e.path = append(e.path, global(i))
+		}
+	} else { // link is previous cell from src
+		for i := l; link[i] != -1; i = link[i] {
+			e.path = append([]int32{global(i)}, e.path...)
+		}
+	}
+	return e, true
+}
+
+// buildIntra computes edges between entrances of cluster c
+func (h *HPA) buildIntra(c int) {
+	ids := h.entrances(c)
+	for _, u := range ids {
+		var inter []hpaEdge
+		for _, e := range h.edges[u] {
+			if e.inter {
+				inter = append(inter, e)
+			}
+		}
+		dist, link, x0, y0, w := h.clusterSearch(c, u, false)
+		for _, v := range ids {
+			if v == u {
+				continue
+			}
+			if e, ok := h.clusterEdge(u, v, dist, link, x0, y0, w, false); ok {
+				inter = append(inter, e)
+			}
+		}
+		h.edges[u] = inter
+	}
+}
+
+// removeBorder removes transitions on the right (or bottom) border of cluster (cx,cy)
+func (h *HPA) removeBorder(cx, cy int, right bool) {
+	a := h.Astar
+	p, q := h.border(cx, cy, right)
+	cells := make(map[int]bool)
+	for i := range p {
+		cells[p[i][1]*a.Width+p[i][0]] = true
+		for _, v := range motion { // q and cells next to q for diagonal moves
+			if x, y := q[i][0]+int(v[0]), q[i][1]+int(v[1]); x >= 0 && y >= 0 && x <= a.MaxX && y <= a.MaxY {
+				cells[y*a.Width+x] = true
+			}
+		}
+	}
+	for u := range cells {
+		es, ok := h.edges[u]
+		if !ok {
+			continue
+		}
+		ux, uy := a.indToPosXY(u)
+		var keep []hpaEdge
+		for _, e := range es {
+			vx, vy := a.indToPosXY(e.to)
+			if !e.inter || !h.crossing(cx, cy, right, [2]int{ux, uy}, [2]int{vx, vy}) {
+				keep = append(keep, e)
+			}
+		}
+		h.edges[u] = keep
+	}
+	// node without transitions is removed
+	for u := range cells {
+		if !h.isEntrance(u) {
+			delete(h.edges, u)
+			x, y := h.Astar.indToPosXY(u)
+			delete(h.members[h.clusterOf(x, y)], u)
+		}
+	}
+}
+
+// isEntrance is true if u has any transition to (or from) other clusters
+func (h *HPA) isEntrance(u int) bool {
+	for _, e := range h.edges[u] {
+		if e.inter {
+			return true
+		}
+	}
+	x, y := h.Astar.indToPosXY(u)
+	for _, v := range motion {
+		for _, e := range h.edges[(y+int(v[1]))*h.Astar.Width+x+int(v[0])] {
+			if e.inter && e.to == u {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateRegion rebuilds clusters overlapping [x0,x1]x[y0,y1] after the cost map is changed
+func (h *HPA) UpdateRegion(x0, y0, x1, y1 int) {
+	x0, y0, x1, y1 = clipRect(h.Astar, x0-1, y0-1, x1+1, y1+1)
+	cx0, cy0 := x0/h.ClusterSize, y0/h.ClusterSize
+	cx1, cy1 := x1/h.ClusterSize, y1/h.ClusterSize
+	// borders of changed clusters
+	type border struct {
+		cx, cy int
+		right  bool
+	}
+	var borders []border // in order, so the edges are same in every run
+	seen := make(map[border]bool)
+	add := func(b border) {
+		if !seen[b] {
+			seen[b] = true
+			borders = append(borders, b)
+		}
+	}
+	for cy := cy0; cy <= cy1; cy++ {
+		for cx := cx0; cx <= cx1; cx++ {
+			if cy > 0 {
+				add(border{cx, cy - 1, false})
+			}
+			if cx > 0 {
+				add(border{cx - 1, cy, true})
+			}
+			add(border{cx, cy, true})
+			add(border{cx, cy, false})
+		}
+	}
+	for _, b := range borders {
+		h.removeBorder(b.cx, b.cy, b.right)
+	}
+	for _, b := range borders {
+		h.buildEntrances(b.cx, b.cy, b.right)
+	}
+	for cy := cy0 - 1; cy <= cy1+1; cy++ {
+		for cx := cx0 - 1; cx <= cx1+1; cx++ {
+			if cx >= 0 && cy >= 0 && cx < h.CW && cy < h.CH {
+				h.buildIntra(cy*h.CW + cx)
+			}
+		}
+	}
+}
+
+// MapChanged updates the abstract graph (HPA can be a MapListener)
+func (h *HPA) MapChanged(a *Astar, x0, y0, x1, y1 int) {
+	h.UpdateRegion(x0, y0, x1, y1)
+}
+
+// Plan searches the abstract graph and refines the route to cells (Plan format) with its cost.
+// the route is near optimal (optimal inside a cluster or two neighbor clusters, through entrances between others).
+func (h *HPA) Plan(sx, sy, gx, gy int) ([][2]int, float64, error) {
+	a := h.Astar
+	if !a.verifyPoint(sx, sy) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	s, g := sy*a.Width+sx, gy*a.Width+gx
+	sc, gc := h.clusterOf(sx, sy), h.clusterOf(gx, gy)
+
+	// connect start and goal to entrances of their clusters
+	var startEdges []hpaEdge
+	dist, link, x0, y0, w := h.clusterSearch(sc, s, false)
+	for _, v := range h.entrances(sc) {
+		if e, ok := h.clusterEdge(s, v, dist, link, x0, y0, w, false); ok && v != s {
+			startEdges = append(startEdges, e)
+		}
+	}
+	if s != g && abs(sc%h.CW-gc%h.CW) <= 1 && abs(sc/h.CW-gc/h.CW) <= 1 {
+		// same or neighbor clusters, also searched directly in both clusters
+		if sc != gc {
+			bx0, by0, bx1, by1 := h.rect(sc)
+			gx0, gy0, gx1, gy1 := h.rect(gc)
+			x0, y0 = minInt(bx0, gx0), minInt(by0, gy0)
+			x1, y1 := maxInt(bx1, gx1), maxInt(by1, gy1)
+			dist, link = a.rectSearch(x0, y0, x1, y1, s, false)
+			w = x1 - x0 + 1
+		}
+		if e, ok := h.clusterEdge(s, g, dist, link, x0, y0, w, false); ok {
+			startEdges = append(startEdges, e)
+		}
+	}
+	goalEdges := make(map[int]hpaEdge)
+	dist, link, x0, y0, w = h.clusterSearch(gc, g, true)
+	for _, v := range h.entrances(gc) {
+		if e, ok := h.clusterEdge(v, g, dist, link, x0, y0, w, true); ok && v != g {
+			goalEdges[v] = e
+		}
+	}
+
+	heur := func(id int) float64 {
+		x, y := a.indToPosXY(id)
+		return math.Hypot(float64(x-gx), float64(y-gy))
+	}
+	cost := map[int]float64{s: 0}
+	prev := make(map[int]hpaEdge)
+	from := map[int]int{s: -1}
+	closed := make(map[int]bool)
+	pq := &priorityQueue{}
+	pq.push(s, heur(s))
+	for pq.Len() > 0 {
+		u := pq.pop().key
+		if closed[u] {
+			continue
+		}
+		closed[u] = true
+		if u == g {
+			break
+		}
+		es := h.edges[u]
+		if u == s {
+			es = append(append([]hpaEdge(nil), es...), startEdges...)
+		}
+		if e, ok := goalEdges[u]; ok {
+			es = append(append([]hpaEdge(nil), es...), e)
+		}
+		for _, e := range es {
+			nc := cost[u] + e.cost
+			if old, ok := cost[e.to]; closed[e.to] || ok && old <= nc {
+				continue
+			}
+			cost[e.to] = nc
+			prev[e.to] = e
+			from[e.to] = u
+			pq.push(e.to, nc+heur(e.to))
+		}
+	}
+	if !closed[g] {
+		return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): goal is not reachable", sx, sy, gx, gy)
+	}
+	// refine: goal first
+	var route [][2]int
+	for v := g; v != s; v = from[v] {
+		p := prev[v].path
+		for i := len(p) - 1; i >= 0; i-- {
+			x, y := a.indToPosXY(int(p[i]))
+			route = append(route, [2]int{x, y})
+		}
+	}
+	route = append(route, [2]int{sx, sy})
+	return route, cost[g], a.checkUnknown(route)
+}
@@ -0,0 +1,126 @@
+package astar_wr
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func checkHPA(t *testing.T, h *HPA, sx, sy, gx, gy int) ([][2]int, float64) {
+	t.Helper()
+	route, cost, err := h.Plan(sx, sy, gx, gy)
+	if err != nil {
+		t.Fatalf("Plan(%d,%d,%d,%d): %v", sx, sy, gx, gy, err)
+	}
+	if route[0] != [2]int{gx, gy} || route[len(route)-1] != [2]int{sx, sy} {
+		t.Errorf("route from %v to %v", route[len(route)-1], route[0])
+	}
+	if c, blocked := h.Astar.RouteCost(route); blocked >= 0 || math.Abs(c-cost) > 1e-9 {
+		t.Errorf("route cost %f (blocked %d), Plan cost %f", c, blocked, cost)
+	}
+	f, _ := h.Astar.Dijkstra(sx, sy)
+	if opt := f.Cost(gx, gy); cost < opt-1e-9 || cost > 1.1*opt {
+		t.Errorf("cost %f, optimal %f", cost, opt)
+	}
+	return route, cost
+}
+
+func TestHPAPlan(t *testing.T) {
+	a := shelfMap()
+	h := NewHPA(a, 10)
+	r1, _ := checkHPA(t, h, 2, 20, 57, 20)
+	checkHPA(t, h, 12, 6, 13, 30) // same aisle
+	checkHPA(t, h, 3, 3, 4, 4)    // same cluster
+	// same graph and route in every build
+	r2, _ := checkHPA(t, NewHPA(a, 10), 2, 20, 57, 20)
+	if !reflect.DeepEqual(r1, r2) {
+		t.Error("routes of two builds are different")
+	}
+}
+
+func TestHPAUpdateRegion(t *testing.T) {
+	a := shelfMap()
+	h := NewHPA(a, 10)
+	a.AddListener(h)
+	// close the top of aisles, open a shelf
+	var cells [][2]int
+	for x := 10; x < 40; x++ {
+		cells = append(cells, [2]int{x, 2})
+	}
+	a.AddObstacles(cells)
+	for y := 15; y < 20; y++ {
+		a.RemoveObstacle(24, y)
+		a.RemoveObstacle(25, y)
+	}
+	rebuilt := NewHPA(a, 10)
+	for _, c := range [][4]int{{2, 20, 57, 20}, {20, 1, 30, 1}, {12, 6, 28, 30}} {
+		_, c1 := checkHPA(t, h, c[0], c[1], c[2], c[3])
+		_, c2 := checkHPA(t, rebuilt, c[0], c[1], c[2], c[3])
+		if math.Abs(c1-c2) > 1e-9 {
+			t.Errorf("Plan%v: updated cost %f, rebuilt cost %f", c, c1, c2)
+		}
+	}
+}
+
+func TestHPADiagonalEntrance(t *testing.T) {
+	// corridor of three clusters, the borders are crossed only diagonally
+	// (7,3)->(8,4) and (15,4)->(16,5)
+	free := make(map[[2]int]bool)
+	for x := 0; x < 24; x++ {
+		free[[2]int{x, 3 + x/8}] = true
+	}
+	var objects [][2]int
+	for x := 0; x < 24; x++ {
+		for y := 0; y < 8; y++ {
+			if !free[[2]int{x, y}] {
+				objects = append(objects, [2]int{x, y})
+			}
+		}
+	}
+	a := NewWeightedAstar(objects, 24, 8, 0)
+	h := NewHPA(a, 8)
+	route, _ := checkHPA(t, h, 0, 3, 23, 5)
+	if len(route) != 24 {
+		t.Errorf("route length %d, want 24", len(route))
+	}
+	// one-way diagonal is kept in its direction
+	a.Directions = NewDirectionMap(a)
+	a.Directions.SetOneWay(8, 4, 0, -1)
+	h = NewHPA(a, 8)
+	checkHPA(t, h, 0, 3, 23, 5)
+	if _, _, err := h.Plan(23, 5, 0, 3); err == nil {
+		t.Error("Plan against one-way diagonal succeeded")
+	}
+}
+
+func TestHPAReachable(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		a := randomMap(40, 30, 0.25, seed)
+		if seed%2 == 1 {
+			randomOneWay(a, 0.1, seed)
+		}
+		h := NewHPA(a, 8)
+		rnd := rand.New(rand.NewSource(seed))
+		for i := 0; i < 10; i++ {
+			s, _ := a.samplePoint(rnd)
+			g, _ := a.samplePoint(rnd)
+			f, _ := a.Dijkstra(s[0], s[1])
+			opt := f.Cost(g[0], g[1])
+			route, cost, err := h.Plan(s[0], s[1], g[0], g[1])
+			if math.IsInf(opt, 1) {
+				if err == nil {
+					t.Errorf("seed %d: Plan%v%v found not reachable goal", seed, s, g)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("seed %d: Plan%v%v: %v", seed, s, g, err)
+				continue
+			}
+			if c, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(c-cost) > 1e-9 || cost < opt-1e-9 {
+				t.Errorf("seed %d: Plan%v%v cost %f, route cost %f (blocked %d), optimal %f", seed, s, g, cost, c, blocked, opt)
+			}
+		}
+	}
+}
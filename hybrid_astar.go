@@ -0,0 +1,361 @@
+package astar_wr
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Hybrid A* planning for car-like vehicles (tuggers with minimum turning radius)
+// continuous (x, y, theta) states are binned on the Astar grid cells.
+
+// Pose is a continuous vehicle pose on the grid (Theta in radian).
+type Pose struct {
+	X       float64
+	Y       float64
+	Theta   float64
+	Reverse bool // reached by reverse driving
+}
+
+// Vehicle is a kinematic bicycle model (lengths in cells)
+type Vehicle struct {
+	WheelBase float64
+	MaxSteer  float64 // max steering angle [rad]
+	Radius    float64 // footprint radius for collision check (0: point)
+}
+
+// MinTurnRadius returns minimum turning radius of the vehicle.
+func (v Vehicle) MinTurnRadius() float64 {
+	return v.WheelBase / math.Tan(v.MaxSteer)
+}
+
+// HybridAstar planner, parameters can be changed after NewHybridAstar.
+type HybridAstar struct {
+	Astar   *Astar
+	Vehicle Vehicle
+
+	ThetaBins    int     // heading resolution
+	StepSize     float64 // arc length of one expansion [cell]
+	SteerSamples int     // number of steering angles for expansion
+	AllowReverse bool    // reverse expansion & Reeds-Shepp shot (Dubins if false)
+
+	ReversePenalty     float64 // multiplier for reverse driving
+	SwitchPenalty      float64 // cost for changing direction
+	SteerPenalty       float64 // cost for steering
+	SteerChangePenalty float64 // cost for changing steering angle
+	CostWeight         float64 // weight of CostMap value as obstacle penalty
+
+	AnalyticInterval int     // try analytic shot every N expansion
+	GoalDist         float64 // goal tolerance [cell]
+	GoalAngle        float64 // goal tolerance [rad]
+	MaxExpand        int     // max number of expansion
+}
+
+type hybridNode struct {
+	pose   Pose
+	cost   float64
+	steer  float64
+	parent int
+	path   []Pose // poses from parent to this node
+}
+
+// NewHybridAstar returns planner with default parameters
+func NewHybridAstar(a *Astar, v Vehicle) *HybridAstar {
+	return &HybridAstar{
+		Astar:              a,
+		Vehicle:            v,
+		ThetaBins:          72,
+		StepSize:           1.5,
+		SteerSamples:       5,
+		AllowReverse:       true,
+		ReversePenalty:     2.0,
+		SwitchPenalty:      10.0,
+		SteerPenalty:       0.5,
+		SteerChangePenalty: 1.0,
+		CostWeight:         1.0,
+		AnalyticInterval:   5,
+		GoalDist:           1.0,
+		GoalAngle:          math.Pi / 36,
+		MaxExpand:          200000,
+	}
+}
+
+func (h *HybridAstar) poseKey(p Pose) int {
+	a := h.Astar
+	ix := int(math.Round(p.X))
+	iy := int(math.Round(p.Y))
+	tb := int(mod2pi(p.Theta)/(2*math.Pi)*float64(h.ThetaBins)) % h.ThetaBins
+	return (tb*a.Height+iy)*a.Width + ix
+}
+
+// cellCost returns obstacle penalty of the pose, ok is false on collision.
+func (h *HybridAstar) cellCost(p Pose) (float64, bool) {
+	a := h.Astar
+	ix := int(math.Round(p.X))
+	iy := int(math.Round(p.Y))
+	if ix < 0 || iy < 0 || ix >= a.Width || iy >= a.Height || a.CostMap[ix][iy] == 0xff {
+		return 0, false
+	}
+	r := int(math.Ceil(h.Vehicle.Radius))
+	for dx := -r; dx <= r; dx++ {
+		for dy := -r; dy <= r; dy++ {
+			if float64(dx*dx+dy*dy) > h.Vehicle.Radius*h.Vehicle.Radius {
+				continue
+			}
+			x, y := ix+dx, iy+dy
+			if x < 0 || y < 0 || x >= a.Width || y >= a.Height || a.CostMap[x][y] == 0xff {
+				return 0, false
+			}
+		}
+	}
+	return float64(a.CostMap[ix][iy]), true
+}
+
+// simulate bicycle model with steer for distance dist (negative: reverse)
+func (h *HybridAstar) simulate(p Pose, steer, dist float64) ([]Pose, float64, bool) {
+	n := int(math.Ceil(math.Abs(dist) / 0.5))
+	dl := dist / float64(n)
+	poses := make([]Pose, 0, n)
+	penalty := 0.0
+	x, y, th := p.X, p.Y, p.Theta
+	prev := p
+	for i := 0; i < n; i++ {
+		if math.Abs(steer) < 1e-6 {
+			x += dl * math.Cos(th)
+			y += dl * math.Sin(th)
+		} else {
+			r := h.Vehicle.WheelBase / math.Tan(steer)
+			nth := th + dl/r
+			x += r * (math.Sin(nth) - math.Sin(th))
+			y -= r * (math.Cos(nth) - math.Cos(th))
+			th = nth
+		}
+		np := Pose{X: x, Y: y, Theta: normAngle(th), Reverse: dist < 0}
+		c, ok := h.cellCost(np)
+		if !ok {
+			return nil, 0, false
+		}
+		penalty += c * math.Abs(dl)
+		dc, ok := h.directionCost(prev, np)
+		if !ok {
+			return nil, 0, false
+		}
+		penalty += dc
+		poses = append(poses, np)
+		prev = np
+	}
+	return poses, penalty, true
+}
+
+// directionCost checks one-way rule (Astar.Directions) between two poses.
+func (h *HybridAstar) directionCost(p, np Pose) (float64, bool) {
+	d := h.Astar.Directions
+	x, y := int(math.Round(p.X)), int(math.Round(p.Y))
+	nx, ny := int(math.Round(np.X)), int(math.Round(np.Y))
+	if d == nil || (x == nx && y == ny) {
+		return 0, true
+	}
+	heading := np.Theta
+	if np.Reverse {
+		heading += math.Pi
+	}
+	return d.moveCost(x, y, nx, ny, motionIndex(heading))
+}
+
+// analyticShot tries Dubins / Reeds-Shepp curve from p to goal.
+func (h *HybridAstar) analyticShot(p, goal Pose) ([]Pose, bool) {
+	rho := h.Vehicle.MinTurnRadius()
+	curve, ok := shortestCurve(p, goal, rho, h.AllowReverse)
+	if !ok {
+		return nil, false
+	}
+	poses := sampleCurve(p, curve, rho, 0.5)
+	prev := p
+	for _, q := range poses {
+		if _, ok := h.cellCost(q); !ok {
+			return nil, false
+		}
+		if _, ok := h.directionCost(prev, q); !ok {
+			return nil, false
+		}
+		prev = q
+	}
+	return poses, true
+}
+
+func (h *HybridAstar) heuristic(p, goal Pose, holo []float64, weight float64) float64 {
+	a := h.Astar
+	d := math.Hypot(p.X-goal.X, p.Y-goal.Y)
+	if hc := holo[int(math.Round(p.Y))*a.Width+int(math.Round(p.X))]; hc > d {
+		d = hc
+	}
+	if curve, ok := shortestCurve(p, goal, h.Vehicle.MinTurnRadius(), h.AllowReverse); ok {
+		if l := curve.length() * h.Vehicle.MinTurnRadius(); l > d {
+			d = l
+		}
+	}
+	return weight * d
+}
+
+// holonomicCost computes cost-to-go from goal cell to every cell ignoring heading.
+func (h *HybridAstar) holonomicCost(gx, gy int) []float64 {
+	a := h.Astar
+	dist := make([]float64, a.Width*a.Height)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	start := gy*a.Width + gx
+	dist[start] = 0
+	pq := &priorityQueue{}
+	pq.push(start, 0)
+	for pq.Len() > 0 {
+		it := pq.pop()
+		if it.priority > dist[it.key] {
+			continue
+		}
+		cx, cy := a.indToPosXY(it.key)
+		for _, v := range motion {
+			nx := cx + int(v[0])
+			ny := cy + int(v[1])
+			if nx < 0 || ny < 0 || nx >= a.Width || ny >= a.Height || a.CostMap[nx][ny] == 0xff {
+				continue
+			}
+			nd := it.priority + v[2] + h.CostWeight*float64(a.CostMap[nx][ny])*v[2]
+			nId := ny*a.Width + nx
+			if nd < dist[nId] {
+				dist[nId] = nd
+				pq.push(nId, nd)
+			}
+		}
+	}
+	return dist
+}
+
+func (h *HybridAstar) isGoal(p, goal Pose) bool {
+	return math.Hypot(p.X-goal.X, p.Y-goal.Y) <= h.GoalDist &&
+		math.Abs(normAngle(p.Theta-goal.Theta)) <= h.GoalAngle
+}
+
+// Plan searches drivable path from start to goal.
+// returned poses are in driving order (start first), see PoseRoute for Plan format.
+// as Plan, *UnknownSpaceError is returned with the path if it passes unknown cells.
+func (h *HybridAstar) Plan(start, goal Pose, weight float64) ([]Pose, error) {
+	a := h.Astar
+	if _, ok := h.cellCost(start); !ok {
+		return nil, fmt.Errorf("start pose (%.1f, %.1f) is not verified", start.X, start.Y)
+	}
+	if _, ok := h.cellCost(goal); !ok {
+		return nil, fmt.Errorf("goal pose (%.1f, %.1f) is not verified", goal.X, goal.Y)
+	}
+	holo := h.holonomicCost(int(math.Round(goal.X)), int(math.Round(goal.Y)))
+	if math.IsInf(holo[int(math.Round(start.Y))*a.Width+int(math.Round(start.X))], 1) {
+		return nil, fmt.Errorf("fail searching pose from (%.1f,%.1f) to (%.1f, %.1f): goal is not reachable", start.X, start.Y, goal.X, goal.Y)
+	}
+
+	nodes := make(map[int]*hybridNode)
+	closed := make(map[int]bool)
+	sKey := h.poseKey(start)
+	nodes[sKey] = &hybridNode{pose: start, parent: -1}
+	pq := &priorityQueue{}
+	pq.push(sKey, h.heuristic(start, goal, holo, weight))
+
+	steers := make([]float64, h.SteerSamples)
+	for i := range steers {
+		if h.SteerSamples == 1 {
+			break
+		}
+		steers[i] = -h.Vehicle.MaxSteer + 2*h.Vehicle.MaxSteer*float64(i)/float64(h.SteerSamples-1)
+	}
+	dirs := []float64{1}
+	if h.AllowReverse {
+		dirs = append(dirs, -1)
+	}
+
+	expand := 0
+	for pq.Len() > 0 && expand < h.MaxExpand {
+		cKey := pq.pop().key
+		if closed[cKey] {
+			continue
+		}
+		closed[cKey] = true
+		current := nodes[cKey]
+		expand++
+		if a.UpdateObj != nil {
+			a.Current = newNode(int(math.Round(current.pose.X)), int(math.Round(current.pose.Y)), current.cost, -1)
+			a.UpdateObj.UpdateAstar(a, color.RGBA{0xa0, 0xb0, 0xb0, 0xff}, 0)
+		}
+
+		if h.isGoal(current.pose, goal) {
+			path := h.finalPath(nodes, cKey, nil)
+			return path, a.checkUnknown(PoseRoute(path))
+		}
+		if h.AnalyticInterval > 0 && expand%h.AnalyticInterval == 0 {
+			if shot, ok := h.analyticShot(current.pose, goal); ok {
+				path := h.finalPath(nodes, cKey, shot)
+				return path, a.checkUnknown(PoseRoute(path))
+			}
+		}
+
+		for _, dir := range dirs {
+			for _, steer := range steers {
+				poses, penalty, ok := h.simulate(current.pose, steer, dir*h.StepSize)
+				if !ok {
+					continue
+				}
+				last := poses[len(poses)-1]
+				nKey := h.poseKey(last)
+				if closed[nKey] {
+					continue
+				}
+				cost := current.cost + h.StepSize + h.CostWeight*penalty
+				if dir < 0 {
+					cost += h.StepSize * (h.ReversePenalty - 1)
+				}
+				if current.parent != -1 && current.pose.Reverse != last.Reverse {
+					cost += h.SwitchPenalty
+				}
+				cost += h.SteerPenalty*math.Abs(steer) + h.SteerChangePenalty*math.Abs(steer-current.steer)
+				if old, ok := nodes[nKey]; ok && old.cost <= cost {
+					continue
+				}
+				nodes[nKey] = &hybridNode{pose: last, cost: cost, steer: steer, parent: cKey, path: poses}
+				pq.push(nKey, cost+h.heuristic(last, goal, holo, weight))
+			}
+		}
+	}
+	return nil, fmt.Errorf("fail searching pose from (%.1f,%.1f) to (%.1f, %.1f): expanded %d nodes", start.X, start.Y, goal.X, goal.Y, expand)
+}
+
+func (h *HybridAstar) finalPath(nodes map[int]*hybridNode, key int, shot []Pose) []Pose {
+	rev := make([]Pose, 0)
+	for i := len(shot) - 1; i >= 0; i-- {
+		rev = append(rev, shot[i])
+	}
+	for key != -1 {
+		n := nodes[key]
+		if n.parent == -1 {
+			rev = append(rev, n.pose)
+		}
+		for i := len(n.path) - 1; i >= 0; i-- {
+			rev = append(rev, n.path[i])
+		}
+		key = n.parent
+	}
+	path := make([]Pose, len(rev))
+	for i := range rev {
+		path[i] = rev[len(rev)-1-i]
+	}
+	return path
+}
+
+// PoseRoute converts hybrid path into Plan style route (goal first, start last).
+func PoseRoute(path []Pose) (route [][2]int) {
+	for i := len(path) - 1; i >= 0; i-- {
+		p := [2]int{int(math.Round(path[i].X)), int(math.Round(path[i].Y))}
+		if len(route) > 0 && route[len(route)-1] == p {
+			continue
+		}
+		route = append(route, p)
+	}
+	return route
+}
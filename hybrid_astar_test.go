@@ -0,0 +1,54 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHybridAstarReachesGoal(t *testing.T) {
+	var objects [][2]int
+	for y := 0; y < 25; y++ {
+		objects = append(objects, [2]int{20, y}) // wall with an opening at the bottom
+	}
+	objects = append(objects, [2]int{39, 39}) // map size
+	a := WeightedAstar(objects, 1)
+	v := Vehicle{WheelBase: 2, MaxSteer: 0.5}
+	start := Pose{X: 5, Y: 5}
+	goal := Pose{X: 35, Y: 5, Theta: math.Pi / 2}
+	for _, reverse := range []bool{true, false} {
+		h := NewHybridAstar(a, v)
+		h.AllowReverse = reverse
+		path, err := h.Plan(start, goal, 1)
+		if err != nil {
+			t.Fatalf("reverse=%v: %v", reverse, err)
+		}
+		last := path[len(path)-1]
+		if math.Hypot(last.X-goal.X, last.Y-goal.Y) > h.GoalDist || math.Abs(normAngle(last.Theta-goal.Theta)) > h.GoalAngle {
+			t.Errorf("reverse=%v: path ends at %+v, want %+v", reverse, last, goal)
+		}
+		for _, p := range path {
+			if _, ok := h.cellCost(p); !ok {
+				t.Errorf("reverse=%v: pose %+v collides", reverse, p)
+			}
+			if !reverse && p.Reverse {
+				t.Errorf("pose %+v is reverse driving", p)
+			}
+		}
+	}
+}
+
+func TestHybridAstarBlocked(t *testing.T) {
+	var objects [][2]int
+	for y := 0; y < 40; y++ {
+		objects = append(objects, [2]int{20, y})
+	}
+	objects = append(objects, [2]int{39, 39})
+	a := WeightedAstar(objects, 1)
+	h := NewHybridAstar(a, Vehicle{WheelBase: 2, MaxSteer: 0.5})
+	if _, err := h.Plan(Pose{X: 5, Y: 5}, Pose{X: 35, Y: 5}, 1); err == nil {
+		t.Error("Plan through the wall should fail")
+	}
+	if _, err := h.Plan(Pose{X: 5, Y: 5}, Pose{X: 20, Y: 5}, 1); err == nil {
+		t.Error("Plan to the wall should fail")
+	}
+}
@@ -0,0 +1,150 @@
+package astar_wr
+
+// Live cost map update
+// changes are made on Astar.Layers, re-inflated locally and notified to listeners.
+
+// MapListener is notified when CostMap is changed in [x0,x1]x[y0,y1]
+// (planners, caches, visualizers). Astar.Version is already incremented.
+type MapListener interface {
+	MapChanged(a *Astar, x0, y0, x1, y1 int)
+}
+
+// AddListener subscribes map change notification
+func (a *Astar) AddListener(l MapListener) {
+	a.listeners = append(a.listeners, l)
+}
+
+// RemoveListener unsubscribes map change notification
+func (a *Astar) RemoveListener(l MapListener) {
+	for i, li := range a.listeners {
+		if li == l {
+			a.listeners = append(a.listeners[:i], a.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// NotifyChanged increments map version and notifies listeners.
+// call this after CostMap (or Directions) is changed directly.
+func (a *Astar) NotifyChanged(x0, y0, x1, y1 int) {
+	a.Version++
+	for _, l := range a.listeners {
+		l.MapChanged(a, x0, y0, x1, y1)
+	}
+}
+
+func (a *Astar) isListener(l MapListener) bool {
+	for _, li := range a.listeners {
+		if li == l {
+			return true
+		}
+	}
+	return false
+}
+
+// layers returns Astar.Layers, made from current CostMap if nil
+func (a *Astar) layers() *LayeredCostMap {
+	if a.Layers == nil {
+		NewLayeredCostMap(a, nil, a.Iteration)
+	}
+	return a.Layers
+}
+
+// AddObstacle adds obstacle (e.g. dropped pallet) on the dynamic layer and re-inflates around it.
+func (a *Astar) AddObstacle(x, y int) {
+	m := a.layers()
+	m.Layer(LayerDynamic).Set(x, y, 0xff)
+	m.UpdateRegion(x, y, x, y)
+}
+
+// AddObstacles adds obstacle cells at once (one update for the bounding box)
+func (a *Astar) AddObstacles(cells [][2]int) {
+	if len(cells) == 0 {
+		return
+	}
+	m := a.layers()
+	l := m.Layer(LayerDynamic)
+	for _, c := range cells {
+		l.Set(c[0], c[1], 0xff)
+	}
+	m.UpdateRegion(bbox(cells))
+}
+
+// RemoveObstacle removes obstacle of the cell (both static and dynamic)
+func (a *Astar) RemoveObstacle(x, y int) {
+	m := a.layers()
+	m.Layer(LayerDynamic).Clear(x, y)
+	static := m.Layer(LayerStatic)
+	if c, ok := static.Get(x, y); ok && c == 0xff {
+		static.Clear(x, y)
+	}
+	m.UpdateRegion(x, y, x, y)
+}
+
+// SetCost sets cost of the cell on the dynamic layer, higher static cost of the cell is removed
+// so the cell gets the cost (obstacles nearby still inflate it).
+func (a *Astar) SetCost(x, y int, cost byte) {
+	m := a.layers()
+	m.Layer(LayerDynamic).Set(x, y, cost)
+	static := m.Layer(LayerStatic)
+	if c, ok := static.Get(x, y); ok && c > int(cost) {
+		static.Clear(x, y)
+	}
+	m.UpdateRegion(x, y, x, y)
+}
+
+// SetCostRegion sets cost of cells in [x0,x1]x[y0,y1] on the dynamic layer.
+// the cost is combined with static cost by max, cost 0xff makes obstacles.
+func (a *Astar) SetCostRegion(x0, y0, x1, y1 int, cost byte) {
+	m := a.layers()
+	l := m.Layer(LayerDynamic)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			l.Set(x, y, cost)
+		}
+	}
+	m.UpdateRegion(x0, y0, x1, y1)
+}
+
+// ClearCostRegion removes dynamic costs (and obstacles) in [x0,x1]x[y0,y1]
+func (a *Astar) ClearCostRegion(x0, y0, x1, y1 int) {
+	m := a.layers()
+	l := m.Layer(LayerDynamic)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			l.Clear(x, y)
+		}
+	}
+	m.UpdateRegion(x0, y0, x1, y1)
+}
+
+func bbox(cells [][2]int) (int, int, int, int) {
+	x0, y0 := cells[0][0], cells[0][1]
+	x1, y1 := x0, y0
+	for _, c := range cells[1:] {
+		if c[0] < x0 {
+			x0 = c[0]
+		}
+		if c[0] > x1 {
+			x1 = c[0]
+		}
+		if c[1] < y0 {
+			y0 = c[1]
+		}
+		if c[1] > y1 {
+			y1 = c[1]
+		}
+	}
+	return x0, y0, x1, y1
+}
+
+// MapChanged repairs the search tree for changed cells (D* Lite can be a MapListener)
+func (d *DStarLite) MapChanged(a *Astar, x0, y0, x1, y1 int) {
+	cells := make([][2]int, 0, (x1-x0+1)*(y1-y0+1))
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			cells = append(cells, [2]int{x, y})
+		}
+	}
+	d.CellsChanged(cells)
+}
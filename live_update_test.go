@@ -0,0 +1,55 @@
+package astar_wr
+
+import "testing"
+
+type countListener struct {
+	calls int
+}
+
+func (l *countListener) MapChanged(a *Astar, x0, y0, x1, y1 int) {
+	l.calls++
+	if x0 < 0 || y0 < 0 || x1 > a.MaxX || y1 > a.MaxY || x0 > x1 || y0 > y1 {
+		panic("wrong region")
+	}
+}
+
+func TestUpdateOutOfMap(t *testing.T) {
+	a := NewWeightedAstar(nil, 20, 20, 2)
+	NewLayeredCostMap(a, nil, 2)
+	l := &countListener{}
+	a.AddListener(l)
+	a.AddObstacle(-10, -10)
+	a.AddObstacle(500, 5)
+	a.AddObstacles([][2]int{{-5, 3}, {-3, 30}})
+	a.SetCostRegion(30, 30, 40, 40, 100)
+	a.ClearCostRegion(-20, -20, -10, -10)
+	if l.calls != 0 {
+		t.Errorf("listener is called %d times for out of map changes", l.calls)
+	}
+	// partly out of the map
+	a.SetCostRegion(-5, -5, 1, 1, 100)
+	if l.calls != 1 || a.CostMap[0][0] != 100 || a.CostMap[1][1] != 100 {
+		t.Errorf("calls %d, cost %d %d", l.calls, a.CostMap[0][0], a.CostMap[1][1])
+	}
+}
+
+func TestSetCostNotifies(t *testing.T) {
+	a := NewWeightedAstar([][2]int{{5, 5}}, 20, 20, 2)
+	NewLayeredCostMap(a, [][2]int{{5, 5}}, 2)
+	l := &countListener{}
+	a.AddListener(l)
+	version := a.Version
+	a.SetCost(5, 5, 10) // lower than the static obstacle
+	if a.CostMap[5][5] != 10 || a.Version == version || l.calls != 1 {
+		t.Errorf("cost %d, version %d (was %d), calls %d", a.CostMap[5][5], a.Version, version, l.calls)
+	}
+	a.SetCost(10, 10, 0xff)
+	if a.CostMap[10][10] != 0xff || a.CostMap[11][10] != inflationCost(1, 2) {
+		t.Errorf("obstacle cost %d, inflation %d", a.CostMap[10][10], a.CostMap[11][10])
+	}
+	// the next update keeps the cost
+	a.AddObstacle(0, 0)
+	if a.CostMap[5][5] != 10 || a.CostMap[10][10] != 0xff {
+		t.Errorf("costs are changed to %d, %d by other update", a.CostMap[5][5], a.CostMap[10][10])
+	}
+}
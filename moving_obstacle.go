@@ -0,0 +1,112 @@
+package astar_wr
+
+import "math"
+
+// Moving obstacles (humans, manual forklifts) with predicted trajectories,
+// avoided by space-time search. one time step is one move of the planner.
+
+// MovingObstacle is a predicted circle, Path[t] is the center at time t (cell unit)
+type MovingObstacle struct {
+	Name   string
+	Radius float64 // including safety margin
+	Path   [][2]float64
+	Stay   bool // stays at the last position after the prediction (otherwise gone)
+}
+
+// LinearObstacle predicts constant velocity motion from (x,y) for steps
+func LinearObstacle(name string, x, y, vx, vy, radius float64, steps int) MovingObstacle {
+	o := MovingObstacle{Name: name, Radius: radius, Path: make([][2]float64, steps+1)}
+	for t := range o.Path {
+		o.Path[t] = [2]float64{x + vx*float64(t), y + vy*float64(t)}
+	}
+	return o
+}
+
+// At returns predicted center at time t (ok is false if not present)
+func (o *MovingObstacle) At(t float64) ([2]float64, bool) {
+	n := len(o.Path)
+	if n == 0 || t < 0 {
+		return [2]float64{}, false
+	}
+	if t >= float64(n-1) {
+		if t > float64(n-1) && !o.Stay {
+			return [2]float64{}, false
+		}
+		return o.Path[n-1], true
+	}
+	i := int(t)
+	f := t - float64(i)
+	p, q := o.Path[i], o.Path[i+1]
+	return [2]float64{p[0] + (q[0]-p[0])*f, p[1] + (q[1]-p[1])*f}, true
+}
+
+// Covers returns true if (x,y) is inside the obstacle at time t
+func (o *MovingObstacle) Covers(x, y float64, t float64) bool {
+	p, ok := o.At(t)
+	return ok && math.Hypot(x-p[0], y-p[1]) <= o.Radius
+}
+
+// movingConstraint is spaceTimeConstraint of moving obstacles (and reservation table)
+type movingConstraint struct {
+	obstacles []MovingObstacle
+	table     *ReservationTable
+}
+
+func (c *movingConstraint) vertexBlocked(x, y, t int) bool {
+	if c.table != nil && c.table.vertexBlocked(x, y, t) {
+		return true
+	}
+	for i := range c.obstacles {
+		if c.obstacles[i].Covers(float64(x), float64(y), float64(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeBlocked checks swap in the table and the middle of the move against obstacles
+func (c *movingConstraint) edgeBlocked(x, y, nx, ny, t int) bool {
+	if c.table != nil && c.table.edgeBlocked(x, y, nx, ny, t) {
+		return true
+	}
+	mx, my := float64(x+nx)/2, float64(y+ny)/2
+	for i := range c.obstacles {
+		if c.obstacles[i].Covers(mx, my, float64(t)+0.5) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *movingConstraint) lastBlocked(x, y int) int {
+	last := -1
+	if c.table != nil {
+		last = c.table.lastBlocked(x, y)
+	}
+	for i := range c.obstacles {
+		o := &c.obstacles[i]
+		n := len(o.Path)
+		if n == 0 {
+			continue
+		}
+		if o.Stay && o.Covers(float64(x), float64(y), float64(n-1)) {
+			return parkForever
+		}
+		for t := n - 1; t > last; t-- {
+			if o.Covers(float64(x), float64(y), float64(t)) {
+				last = t
+				break
+			}
+		}
+	}
+	return last
+}
+
+// PlanAvoiding plans timed route from (sx,sy) to (gx,gy) avoiding moving obstacles.
+// the robot waits when no detour exists. route cost includes waits (cost 1 per step).
+func (a *Astar) PlanAvoiding(sx, sy, gx, gy int, weight float64, obstacles []MovingObstacle) (TimedRoute, float64, error) {
+	p := NewPrioritizedPlanner(a)
+	p.Weight = weight
+	p.Obstacles = obstacles
+	return p.Plan(sx, sy, gx, gy)
+}
@@ -0,0 +1,31 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlanAvoidingCrossing(t *testing.T) {
+	a := NewWeightedAstar(nil, 30, 30, 0)
+	// a human walks down across the straight route
+	obs := []MovingObstacle{LinearObstacle("human", 15, 0, 0, 1, 2, 40)}
+	tr, cost, err := a.PlanAvoiding(0, 15, 29, 15, 1, obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr[0] != [2]int{0, 15} || tr[len(tr)-1] != [2]int{29, 15} {
+		t.Errorf("route from %v to %v", tr[0], tr[len(tr)-1])
+	}
+	for tm, c := range tr {
+		if obs[0].Covers(float64(c[0]), float64(c[1]), float64(tm)) {
+			t.Errorf("(%d, %d) is covered at %d", c[0], c[1], tm)
+		}
+	}
+	if c := a.timedCost(tr, 1); math.Abs(c-cost) > 1e-9 {
+		t.Errorf("cost %f, timedCost %f", cost, c)
+	}
+	// the straight route would meet the human at (15,15)
+	if straight := 29.0; cost <= straight {
+		t.Errorf("cost %f is not more than straight route", cost)
+	}
+}
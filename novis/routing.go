@@ -28,6 +28,10 @@ var (
 	weight    = flag.Float64("hweight", 0.5, "Weight of Astar heuristic (0->no dist)")
 	iteration = flag.Int("iteration", 6, "Iteration for Object range delusion")
 	optimize  = flag.Bool("optimize", false, "Optimize route")
+	zoneFile  = flag.String("zones", "", "Zone file (keep-out / slow / lane polygons, json or GeoJSON)")
+	grayCost  = flag.Bool("graycost", false, "Use gray level of the image as traversal cost")
+	snap      = flag.Float64("snap", 0, "Snap start/goal to nearest traversable cell within the radius")
+	planner   = flag.String("planner", "astar", "Planner (astar, prm, rrtstar)")
 
 //	raduis  = flag.Float64("radius", 2, "Weight object raduis for weight")
 //	oweight = flag.Float64("oweight", 1, "Weight of object radius")
@@ -104,11 +108,58 @@ func main() {
 
 	objects, _ := astar_wr.ObjectMap(imData, 200)
 	aStar := astar_wr.WeightedAstar(objects, *iteration)
+	if *grayCost {
+		opt := astar_wr.DefaultGrayCostOption()
+		opt.Iteration = *iteration
+		aStar = astar_wr.GrayCostAstar(imData, opt)
+		objects = nil // static layer from gray cost map
+	}
+	if *zoneFile != "" {
+		zones, err := astar_wr.LoadZoneFile(*zoneFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := zones.Apply(astar_wr.NewLayeredCostMap(aStar, objects, *iteration)); err != nil {
+			log.Fatal(err)
+		}
+		if err := zones.ApplyDirections(aStar); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	plannerSeed := *seed
+	if plannerSeed < 0 {
+		plannerSeed = time.Now().Unix()
+	}
+	var prm *astar_wr.PRM
+	if *planner == "prm" {
+		prm = astar_wr.NewPRM(aStar, 2000, 10, plannerSeed)
+	}
 
 	//	jstr, _ := json.Marshal(route) //, "", "	")
 	//	fmt.Print("Output:", jstr, "\n")
 	for *rcount > 0 {
-		route, _ := aStar.Plan(X0, Y0, X1, Y1, *weight) //from point(10,10) to point(120,120)
+		if *snap > 0 {
+			for _, p := range []*[2]*int{{&X0, &Y0}, {&X1, &Y1}} {
+				if s, err := aStar.SnapPoint(*p[0], *p[1], *snap); err == nil && s.Moved {
+					log.Printf("Snap %d,%d -> %d,%d", s.From[0], s.From[1], s.To[0], s.To[1])
+					*p[0], *p[1] = s.To[0], s.To[1]
+				}
+			}
+		}
+		var route [][2]int
+		var err error
+		switch *planner {
+		case "prm":
+			route, _, err = prm.Plan(X0, Y0, X1, Y1)
+		case "rrtstar":
+			route, _, err = astar_wr.NewRRTStar(aStar, plannerSeed).Plan(X0, Y0, X1, Y1)
+		default:
+			route, err = aStar.Plan(X0, Y0, X1, Y1, *weight) //from point(10,10) to point(120,120)
+		}
+		if err != nil { // failed, or route passes unknown cells
+			log.Print(err)
+		}
 
 		// optimize?
 		if *optimize { //
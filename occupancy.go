@@ -0,0 +1,179 @@
+package astar_wr
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"math/bits"
+)
+
+// OccupancyGrid is a bitset of obstacle cells (1 bit per cell)
+type OccupancyGrid struct {
+	Width  int
+	Height int
+	bits   []uint64
+}
+
+// NewOccupancyGrid returns empty grid
+func NewOccupancyGrid(width, height int) *OccupancyGrid {
+	return &OccupancyGrid{
+		Width:  width,
+		Height: height,
+		bits:   make([]uint64, (width*height+63)/64),
+	}
+}
+
+func (o *OccupancyGrid) index(x, y int) (int, bool) {
+	if x < 0 || y < 0 || x >= o.Width || y >= o.Height {
+		return 0, false
+	}
+	return y*o.Width + x, true
+}
+
+// Get returns true if (x,y) is occupied (out of grid is occupied)
+func (o *OccupancyGrid) Get(x, y int) bool {
+	i, ok := o.index(x, y)
+	if !ok {
+		return true
+	}
+	return o.bits[i>>6]&(1<<uint(i&63)) != 0
+}
+
+// Set marks (x,y) as occupied
+func (o *OccupancyGrid) Set(x, y int) {
+	if i, ok := o.index(x, y); ok {
+		o.bits[i>>6] |= 1 << uint(i&63)
+	}
+}
+
+// Clear marks (x,y) as free
+func (o *OccupancyGrid) Clear(x, y int) {
+	if i, ok := o.index(x, y); ok {
+		o.bits[i>>6] &^= 1 << uint(i&63)
+	}
+}
+
+// Count returns number of occupied cells
+func (o *OccupancyGrid) Count() int {
+	n := 0
+	for _, b := range o.bits {
+		n += bits.OnesCount64(b)
+	}
+	return n
+}
+
+// Objects returns occupied cells as ObjectMap does
+func (o *OccupancyGrid) Objects() [][2]int {
+	data := make([][2]int, 0, o.Count())
+	for x := 0; x < o.Width; x++ {
+		for y := 0; y < o.Height; y++ {
+			if o.Get(x, y) {
+				data = append(data, [2]int{x, y})
+			}
+		}
+	}
+	return data
+}
+
+// OccupancyMap reads gray-scale image into bitset (pixel < closeThreth is occupied)
+func OccupancyMap(imData image.Image, closeThreth int) *OccupancyGrid {
+	bound := imData.Bounds()
+	o := NewOccupancyGrid(bound.Dx(), bound.Dy())
+	for y := 0; y < o.Height; y++ {
+		for x := 0; x < o.Width; x++ {
+			pixel := color.GrayModel.Convert(imData.At(bound.Min.X+x, bound.Min.Y+y)).(color.Gray).Y
+			if int(pixel) < closeThreth {
+				o.Set(x, y)
+			}
+		}
+	}
+	return o
+}
+
+// Occupancy returns obstacle (COST = 0xff) cells of the Astar as bitset
+func (a *Astar) Occupancy() *OccupancyGrid {
+	o := NewOccupancyGrid(a.Width, a.Height)
+	for x := 0; x < a.Width; x++ {
+		for y := 0; y < a.Height; y++ {
+			if a.CostMap[x][y] == 0xff {
+				o.Set(x, y)
+			}
+		}
+	}
+	return o
+}
+
+// OccupancyAstar generates Astar from bitset, map size is the size of occupancy grid.
+// costs are same as WeightedAstar with iteration.
+func OccupancyAstar(o *OccupancyGrid, iteration int) *Astar {
+	a := newAstar(o.Width, o.Height)
+	for x := 0; x < o.Width; x++ {
+		for y := 0; y < o.Height; y++ {
+			if o.Get(x, y) {
+				a.CostMap[x][y] = 0xff
+			}
+		}
+	}
+	a.inflate(o, iteration)
+	a.Iteration = iteration
+	return a
+}
+
+// ImageAstar generates Astar directly from gray-scale image (pixel < closeThreth is object)
+// map size is the image size, no coordinate list is made.
+func ImageAstar(imData image.Image, closeThreth, iteration int) *Astar {
+	bound := imData.Bounds()
+	W := bound.Dx()
+	H := bound.Dy()
+	log.Printf("file loaded with %dx%d", W, H)
+
+	a := newAstar(W, H)
+	o := NewOccupancyGrid(W, H)
+	for x := 0; x < W; x++ {
+		for y := 0; y < H; y++ {
+			pixel := color.GrayModel.Convert(imData.At(bound.Min.X+x, bound.Min.Y+y)).(color.Gray).Y
+			if int(pixel) < closeThreth {
+				a.CostMap[x][y] = 0xff
+				o.Set(x, y)
+			}
+		}
+	}
+	a.inflate(o, iteration)
+	a.Iteration = iteration
+	return a
+}
+
+// inflate adds WeightedAstar style cost around occupied cells (keeps larger cost)
+func (a *Astar) inflate(o *OccupancyGrid, iteration int) {
+	if iteration <= 0 {
+		return
+	}
+	visited := NewOccupancyGrid(a.Width, a.Height)
+	frontier := make([]int32, 0)
+	for i, b := range o.bits {
+		for ; b != 0; b &= b - 1 {
+			idx := i*64 + bits.TrailingZeros64(b)
+			visited.bits[idx>>6] |= 1 << uint(idx&63)
+			frontier = append(frontier, int32(idx))
+		}
+	}
+	for d := 1; d <= iteration && len(frontier) > 0; d++ {
+		cost := inflationCost(d, iteration)
+		next := make([]int32, 0, len(frontier))
+		for _, idx := range frontier {
+			x, y := int(idx)%a.Width, int(idx)/a.Width
+			for _, v := range motion[:4] {
+				nx, ny := x+int(v[0]), y+int(v[1])
+				if visited.Get(nx, ny) {
+					continue
+				}
+				visited.Set(nx, ny)
+				if cost > a.CostMap[nx][ny] {
+					a.CostMap[nx][ny] = cost
+				}
+				next = append(next, int32(ny*a.Width+nx))
+			}
+		}
+		frontier = next
+	}
+}
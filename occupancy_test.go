@@ -0,0 +1,71 @@
+package astar_wr
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestOccupancyGrid(t *testing.T) {
+	o := NewOccupancyGrid(70, 3) // cells across uint64 words
+	for _, p := range [][2]int{{0, 0}, {63, 0}, {64, 0}, {69, 2}} {
+		o.Set(p[0], p[1])
+	}
+	o.Set(70, 0) // out of grid, ignored
+	o.Set(-1, 1)
+	if o.Count() != 4 {
+		t.Errorf("Count = %d, want 4", o.Count())
+	}
+	for _, p := range [][2]int{{0, 0}, {63, 0}, {64, 0}, {69, 2}} {
+		if !o.Get(p[0], p[1]) {
+			t.Errorf("(%d, %d) is not occupied", p[0], p[1])
+		}
+	}
+	if o.Get(1, 0) || o.Get(62, 0) || o.Get(0, 1) {
+		t.Error("free cell is occupied")
+	}
+	for _, p := range [][2]int{{-1, 0}, {0, -1}, {70, 0}, {0, 3}} {
+		if !o.Get(p[0], p[1]) {
+			t.Errorf("out of grid (%d, %d) is free", p[0], p[1])
+		}
+	}
+	o.Clear(63, 0)
+	o.Clear(70, 0)
+	if o.Get(63, 0) || !o.Get(64, 0) || o.Count() != 3 {
+		t.Error("Clear changed wrong cells")
+	}
+	if want := [][2]int{{0, 0}, {64, 0}, {69, 2}}; !reflect.DeepEqual(o.Objects(), want) {
+		t.Errorf("Objects = %v, want %v", o.Objects(), want)
+	}
+}
+
+func TestOccupancyAstar(t *testing.T) {
+	objects := [][2]int{{3, 3}, {10, 5}, {0, 7}}
+	want := NewWeightedAstar(objects, 15, 8, 2)
+	o := NewOccupancyGrid(15, 8)
+	im := image.NewGray(image.Rect(0, 0, 15, 8))
+	for x := 0; x < 15; x++ {
+		for y := 0; y < 8; y++ {
+			im.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	for _, p := range objects {
+		o.Set(p[0], p[1])
+		im.SetGray(p[0], p[1], color.Gray{Y: 0})
+	}
+	for name, a := range map[string]*Astar{
+		"OccupancyAstar": OccupancyAstar(o, 2),
+		"ImageAstar":     ImageAstar(im, 100, 2),
+	} {
+		if !reflect.DeepEqual(a.CostMap, want.CostMap) {
+			t.Errorf("%s cost map is different from WeightedAstar", name)
+		}
+		if got := a.Occupancy(); !reflect.DeepEqual(got.bits, o.bits) {
+			t.Errorf("%s occupancy is different", name)
+		}
+	}
+	if got := OccupancyMap(im, 100); !reflect.DeepEqual(got.bits, o.bits) {
+		t.Error("OccupancyMap is different")
+	}
+}
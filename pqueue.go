@@ -0,0 +1,32 @@
+package astar_wr
+
+import "container/heap"
+
+// pqItem is an entry of the priority queue used by the heap based planners.
+type pqItem struct {
+	key      int
+	priority float64
+}
+
+// priorityQueue is a min-heap of pqItem ordered by priority.
+type priorityQueue []pqItem
+
+func (pq priorityQueue) Len() int            { return len(pq) }
+func (pq priorityQueue) Less(i, j int) bool  { return pq[i].priority < pq[j].priority }
+func (pq priorityQueue) Swap(i, j int)       { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *priorityQueue) Push(x interface{}) { *pq = append(*pq, x.(pqItem)) }
+func (pq *priorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	it := old[n-1]
+	*pq = old[:n-1]
+	return it
+}
+
+func (pq *priorityQueue) push(key int, priority float64) {
+	heap.Push(pq, pqItem{key: key, priority: priority})
+}
+
+func (pq *priorityQueue) pop() pqItem {
+	return heap.Pop(pq).(pqItem)
+}
@@ -0,0 +1,176 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Cooperative A* : reservation table of (cell, time) and prioritized planning
+
+const parkForever = math.MaxInt32
+
+// ReservationTable keeps (cell, time) occupancies of planned agents
+type ReservationTable struct {
+	cells  map[[3]int]int // x, y, t -> agent id
+	edges  map[[5]int]int // x, y, nx, ny, t -> agent id (move from (x,y) at t)
+	parked map[[2]int]int // cell -> time from which the agent stays
+	last   map[[2]int]int // cell -> last reserved time
+	owned  map[int][]TimedRoute
+}
+
+// NewReservationTable returns empty table
+func NewReservationTable() *ReservationTable {
+	return &ReservationTable{
+		cells:  make(map[[3]int]int),
+		edges:  make(map[[5]int]int),
+		parked: make(map[[2]int]int),
+		last:   make(map[[2]int]int),
+		owned:  make(map[int][]TimedRoute),
+	}
+}
+
+// Reserve reserves timed route of agent id.
+// if park is true, the agent stays on the last cell forever.
+func (rt *ReservationTable) Reserve(id int, route TimedRoute, park bool) {
+	for t, p := range route {
+		rt.cells[[3]int{p[0], p[1], t}] = id
+		if l, ok := rt.last[p]; !ok || t > l {
+			rt.last[p] = t
+		}
+		if t > 0 {
+			q := route[t-1]
+			rt.edges[[5]int{q[0], q[1], p[0], p[1], t - 1}] = id
+		}
+	}
+	if park && len(route) > 0 {
+		rt.parked[route[len(route)-1]] = len(route) - 1
+	}
+	rt.owned[id] = append(rt.owned[id], route)
+}
+
+// Release removes all reservations of agent id
+func (rt *ReservationTable) Release(id int) {
+	routes := rt.owned[id]
+	delete(rt.owned, id)
+	for _, route := range routes {
+		for t, p := range route {
+			if rt.cells[[3]int{p[0], p[1], t}] == id {
+				delete(rt.cells, [3]int{p[0], p[1], t})
+			}
+			if t > 0 {
+				q := route[t-1]
+				if k := [5]int{q[0], q[1], p[0], p[1], t - 1}; rt.edges[k] == id {
+					delete(rt.edges, k)
+				}
+			}
+		}
+		if len(route) > 0 {
+			delete(rt.parked, route[len(route)-1])
+		}
+	}
+	// recompute last reserved time
+	rt.last = make(map[[2]int]int)
+	for k := range rt.cells {
+		p := [2]int{k[0], k[1]}
+		if l, ok := rt.last[p]; !ok || k[2] > l {
+			rt.last[p] = k[2]
+		}
+	}
+}
+
+// IsReserved returns true if (x,y) is occupied at time t
+func (rt *ReservationTable) IsReserved(x, y, t int) bool {
+	return rt.vertexBlocked(x, y, t)
+}
+
+func (rt *ReservationTable) vertexBlocked(x, y, t int) bool {
+	if _, ok := rt.cells[[3]int{x, y, t}]; ok {
+		return true
+	}
+	if pt, ok := rt.parked[[2]int{x, y}]; ok && t >= pt {
+		return true
+	}
+	return false
+}
+
+// edgeBlocked is true if other agent moves (nx,ny)->(x,y) at the same time (swap)
+func (rt *ReservationTable) edgeBlocked(x, y, nx, ny, t int) bool {
+	_, ok := rt.edges[[5]int{nx, ny, x, y, t}]
+	return ok
+}
+
+func (rt *ReservationTable) lastBlocked(x, y int) int {
+	if _, ok := rt.parked[[2]int{x, y}]; ok {
+		return parkForever
+	}
+	if l, ok := rt.last[[2]int{x, y}]; ok {
+		return l
+	}
+	return -1
+}
+
+// PrioritizedPlanner plans agents one by one in space-time, avoiding reserved routes.
+type PrioritizedPlanner struct {
+	Astar    *Astar
+	Table    *ReservationTable
+	Weight   float64 // heuristic weight
+	WaitCost float64 // cost of waiting one step
+	MaxTime  int     // time horizon
+	Park     bool    // agents stay on their goals after arrival
+
+	Obstacles []MovingObstacle // predicted moving obstacles, avoided by all agents
+}
+
+// NewPrioritizedPlanner returns planner with empty reservation table
+func NewPrioritizedPlanner(a *Astar) *PrioritizedPlanner {
+	return &PrioritizedPlanner{
+		Astar:    a,
+		Table:    NewReservationTable(),
+		Weight:   1.0,
+		WaitCost: 1.0,
+		MaxTime:  2000,
+		Park:     true,
+	}
+}
+
+// Plan is space-time version of Astar.Plan, reserved cells are avoided with waits and detours.
+// the route is not reserved (see Solve or Table.Reserve).
+func (p *PrioritizedPlanner) Plan(sx, sy, gx, gy int) (TimedRoute, float64, error) {
+	var h []float64
+	if p.Astar.verifyPoint(gx, gy) {
+		h = p.Astar.costToGo(gx, gy)
+	}
+	var c spaceTimeConstraint = p.Table
+	if len(p.Obstacles) > 0 {
+		c = &movingConstraint{obstacles: p.Obstacles, table: p.Table}
+	}
+	return p.Astar.spaceTimePlan(sx, sy, gx, gy, p.Weight, p.WaitCost, p.MaxTime, c, h)
+}
+
+// Solve plans agents in order (agent i has priority over agent i+1) and reserves their routes.
+// agent id of the table is the index of agents. lower priority agents must get out of the way,
+// so the result depends on the order (not complete like CBS).
+func (p *PrioritizedPlanner) Solve(agents []Agent) ([]TimedRoute, float64, error) {
+	for i, ag := range agents {
+		for j := 0; j < i; j++ {
+			if agents[j].Start == ag.Start {
+				return nil, 0, fmt.Errorf("agent %d and %d have same start (%d, %d)", j, i, ag.Start[0], ag.Start[1])
+			}
+			if agents[j].Goal == ag.Goal {
+				return nil, 0, fmt.Errorf("agent %d and %d have same goal (%d, %d)", j, i, ag.Goal[0], ag.Goal[1])
+			}
+		}
+	}
+	routes := make([]TimedRoute, len(agents))
+	total := 0.0
+	for i, ag := range agents {
+		r, c, err := p.Plan(ag.Start[0], ag.Start[1], ag.Goal[0], ag.Goal[1])
+		if err != nil {
+			return routes, total, fmt.Errorf("agent %d: %v", i, err)
+		}
+		p.Table.Reserve(i, r, p.Park)
+		routes[i] = r
+		total += c
+	}
+	return routes, total, nil
+}
@@ -0,0 +1,64 @@
+package astar_wr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrioritizedCorridorSwap(t *testing.T) {
+	a := corridorMap()
+	agents := []Agent{{Start: [2]int{0, 1}, Goal: [2]int{10, 1}}, {Start: [2]int{10, 1}, Goal: [2]int{0, 1}}}
+	p := NewPrioritizedPlanner(a)
+	routes, total, err := p.Solve(agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTimedRoutes(t, a, agents, routes, total, p.WaitCost)
+	// agent 0 has priority and drives straight
+	if len(routes[0]) != 11 {
+		t.Errorf("agent 0 route %v", routes[0])
+	}
+
+	if !p.Table.IsReserved(10, 1, 1000) {
+		t.Error("parked goal is not reserved")
+	}
+	p.Table.Release(0)
+	for tm, c := range routes[0] {
+		if p.Table.IsReserved(c[0], c[1], tm) && routes[1].At(tm) != c {
+			t.Errorf("(%d, %d) at %d is reserved after release", c[0], c[1], tm)
+		}
+	}
+	if p.Table.IsReserved(10, 1, 1000) {
+		t.Error("parked goal is reserved after release")
+	}
+}
+
+func TestPrioritizedParkedGoal(t *testing.T) {
+	a := NewWeightedAstar(nil, 60, 60, 0)
+	p := NewPrioritizedPlanner(a)
+	p.MaxTime = 300
+	agents := []Agent{{Start: [2]int{0, 0}, Goal: [2]int{30, 30}}, {Start: [2]int{59, 59}, Goal: [2]int{30, 30}}}
+	if _, _, err := p.Solve(agents); err == nil {
+		t.Error("same goal is accepted")
+	}
+	if _, _, err := p.Solve([]Agent{agents[0], {Start: [2]int{0, 0}, Goal: [2]int{5, 5}}}); err == nil {
+		t.Error("same start is accepted")
+	}
+	if _, _, err := p.Solve(agents[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// goal parked by agent 0 fails at once, without searching whole space-time
+	start := time.Now()
+	if _, _, err := p.Plan(59, 59, 30, 30); err == nil {
+		t.Error("route to parked goal is found")
+	}
+	if el := time.Since(start); el > 100*time.Millisecond {
+		t.Errorf("Plan to parked goal took %v", el)
+	}
+	// goal occupied after the time horizon
+	p.Table.Reserve(1, TimedRoute{{50, 50}, {50, 50}}, false)
+	p.MaxTime = 1
+	if _, _, err := p.Plan(49, 50, 50, 50); err == nil {
+		t.Error("route to goal occupied until max time is found")
+	}
+}
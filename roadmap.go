@@ -0,0 +1,350 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Sparse roadmap graph extracted from the cost grid
+// nodes are junctions and ends of aisle centerlines (skeleton of free space), edges keep their cell paths.
+
+// RoadNode is a node of the roadmap
+type RoadNode struct {
+	X, Y      int
+	Clearance float64 // distance to the nearest obstacle [cell]
+}
+
+// RoadEdge is a directed edge of the roadmap, Path is cells from From to To (driving order)
+type RoadEdge struct {
+	From, To  int
+	Cost      float64
+	Clearance float64 // min clearance along the path
+	Path      [][2]int
+}
+
+// Roadmap is a directed graph on the map
+type Roadmap struct {
+	Width, Height int
+	Version       uint64 // Astar.Version of the extraction
+	Nodes         []RoadNode
+	Edges         []RoadEdge
+	out           [][]int       // node -> outgoing edge indexes
+	buckets       map[int][]int // bucket -> nodes (see bucket)
+}
+
+// nodeBucket is the size [cell] of square buckets of the node index
+const nodeBucket = 16
+
+// bucket returns index bucket of (x,y), coordinates are clamped into buckets of the map
+func (r *Roadmap) bucket(x, y int) (int, int) {
+	bw, bh := (r.Width+nodeBucket-1)/nodeBucket, (r.Height+nodeBucket-1)/nodeBucket
+	return minInt(maxInt(x/nodeBucket, 0), bw-1), minInt(maxInt(y/nodeBucket, 0), bh-1)
+}
+
+func (r *Roadmap) bucketKey(bx, by int) int {
+	return by*((r.Width+nodeBucket-1)/nodeBucket) + bx
+}
+
+func (r *Roadmap) addBucket(i int) {
+	if r.buckets == nil {
+		r.buckets = make(map[int][]int)
+	}
+	bx, by := r.bucket(r.Nodes[i].X, r.Nodes[i].Y)
+	k := r.bucketKey(bx, by)
+	r.buckets[k] = append(r.buckets[k], i)
+}
+
+// AddNode adds node and returns its index
+func (r *Roadmap) AddNode(n RoadNode) int {
+	r.Nodes = append(r.Nodes, n)
+	r.out = append(r.out, nil)
+	r.addBucket(len(r.Nodes) - 1)
+	return len(r.Nodes) - 1
+}
+
+// AddEdge adds directed edge and returns its index
+func (r *Roadmap) AddEdge(e RoadEdge) int {
+	r.Edges = append(r.Edges, e)
+	r.out[e.From] = append(r.out[e.From], len(r.Edges)-1)
+	return len(r.Edges) - 1
+}
+
+// Out returns indexes of outgoing edges of node u
+func (r *Roadmap) Out(u int) []int {
+	return r.out[u]
+}
+
+// index rebuilds adjacency and node index (after decoding)
+func (r *Roadmap) index() {
+	r.out = make([][]int, len(r.Nodes))
+	for i, e := range r.Edges {
+		r.out[e.From] = append(r.out[e.From], i)
+	}
+	r.buckets = nil
+	for i := range r.Nodes {
+		r.addBucket(i)
+	}
+}
+
+// NodesIn returns nodes in [x0,x1]x[y0,y1]
+func (r *Roadmap) NodesIn(x0, y0, x1, y1 int) []int {
+	var nodes []int
+	bx0, by0 := r.bucket(x0, y0)
+	bx1, by1 := r.bucket(x1, y1)
+	for by := by0; by <= by1; by++ {
+		for bx := bx0; bx <= bx1; bx++ {
+			for _, i := range r.buckets[r.bucketKey(bx, by)] {
+				if n := r.Nodes[i]; n.X >= x0 && n.X <= x1 && n.Y >= y0 && n.Y <= y1 {
+					nodes = append(nodes, i)
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// Nearest returns the node nearest to (x,y) in Euclid distance (-1: no node).
+// buckets are searched in rings around (x,y) until no nearer node can be found.
+func (r *Roadmap) Nearest(x, y int) int {
+	best, bi := math.Inf(1), -1
+	if len(r.Nodes) == 0 {
+		return bi
+	}
+	bx, by := r.bucket(x, y)
+	bw, bh := r.bucket(r.Width-1, r.Height-1)
+	for ring := 0; ring <= maxInt(bw, bh); ring++ {
+		// nodes in the ring are farther than (ring-1) buckets
+		if float64((ring-1)*nodeBucket) >= best {
+			break
+		}
+		for dy := -ring; dy <= ring; dy++ {
+			for dx := -ring; dx <= ring; dx++ {
+				if maxInt(abs(dx), abs(dy)) != ring || bx+dx < 0 || by+dy < 0 || bx+dx > bw || by+dy > bh {
+					continue
+				}
+				for _, i := range r.buckets[r.bucketKey(bx+dx, by+dy)] {
+					n := r.Nodes[i]
+					if d := math.Hypot(float64(n.X-x), float64(n.Y-y)); d < best || d == best && i < bi {
+						best, bi = d, i
+					}
+				}
+			}
+		}
+	}
+	return bi
+}
+
+// Clearance computes distance from every cell to the nearest lethal cell or map border
+// (chamfer distance with 1 and sqrt(2) steps), index is y*Width+x.
+func (a *Astar) Clearance() []float64 {
+	dist := make([]float64, a.Width*a.Height)
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			i := y*a.Width + x
+			switch {
+			case a.CostMap[x][y] == 0xff:
+				dist[i] = 0
+			case x == 0 || y == 0 || x == a.MaxX || y == a.MaxY:
+				dist[i] = 1 // outside of the map is obstacle
+			default:
+				dist[i] = math.Inf(1)
+			}
+		}
+	}
+	a.chamfer(dist, nil)
+	return dist
+}
+
+// chamfer propagates distance from cells with finite dist (1 and sqrt(2) steps).
+// if label is not nil, label of the nearest source is also propagated.
+func (a *Astar) chamfer(dist []float64, label []int32) {
+	pq := &priorityQueue{}
+	for i, d := range dist {
+		if !math.IsInf(d, 1) {
+			pq.push(i, d)
+		}
+	}
+	for pq.Len() > 0 {
+		it := pq.pop()
+		if it.priority > dist[it.key] {
+			continue
+		}
+		x, y := a.indToPosXY(it.key)
+		for _, v := range motion {
+			nx, ny := x+int(v[0]), y+int(v[1])
+			if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+				continue
+			}
+			n := ny*a.Width + nx
+			if nd := it.priority + v[2]; nd < dist[n] {
+				dist[n] = nd
+				if label != nil {
+					label[n] = label[it.key]
+				}
+				pq.push(n, nd)
+			}
+		}
+	}
+}
+
+// ExtractRoadmap makes roadmap of aisle centerlines (skeleton of free space, see VoronoiRoadmap).
+// nodes are junctions and ends of centerlines, centerlines longer than spacing cells are split by nodes.
+// small pieces of the skeleton separated from the main graph of their free space are removed.
+func ExtractRoadmap(a *Astar, spacing int) (*Roadmap, error) {
+	if spacing < 2 {
+		return nil, fmt.Errorf("spacing %d is too small", spacing)
+	}
+	v := VoronoiRoadmap(a, nil)
+	if len(v.Roadmap.Nodes) == 0 {
+		return nil, fmt.Errorf("no aisle in the map")
+	}
+	return v.Roadmap.split(a, v.Clearance, spacing).mainParts(a), nil
+}
+
+// split returns roadmap with edges longer than spacing cells split by nodes (clr is clearance of cells).
+// both directions of an edge are split at the same cells.
+func (r *Roadmap) split(a *Astar, clr []float64, spacing int) *Roadmap {
+	s := &Roadmap{Width: r.Width, Height: r.Height, Version: r.Version}
+	for _, n := range r.Nodes {
+		s.AddNode(n)
+	}
+	node := make(map[[2]int]int) // split cell -> node
+	for _, e := range r.Edges {
+		l := len(e.Path) - 1
+		if l <= spacing {
+			s.AddEdge(e)
+			continue
+		}
+		// split points are chosen on the path in one direction for both edges
+		first, last := e.Path[0], e.Path[l]
+		rev := first[1] > last[1] || first[1] == last[1] && first[0] > last[0] ||
+			first == last && (e.Path[1][1] > e.Path[l-1][1] || e.Path[1][1] == e.Path[l-1][1] && e.Path[1][0] > e.Path[l-1][0])
+		parts := (l + spacing - 1) / spacing
+		cuts := []int{0}
+		for k := 1; k < parts; k++ {
+			c := k * l / parts
+			if rev {
+				c = l - (parts-k)*l/parts
+			}
+			cuts = append(cuts, c)
+		}
+		cuts = append(cuts, l)
+		u := e.From
+		for k := 1; k < len(cuts); k++ {
+			path := append([][2]int(nil), e.Path[cuts[k-1]:cuts[k]+1]...)
+			t := e.To
+			if k < len(cuts)-1 {
+				p := path[len(path)-1]
+				var ok bool
+				if t, ok = node[p]; !ok {
+					t = s.AddNode(RoadNode{X: p[0], Y: p[1], Clearance: clr[p[1]*a.Width+p[0]]})
+					node[p] = t
+				}
+			}
+			cost := 0.0
+			for i := 1; i < len(path); i++ {
+				mc, _ := a.moveCost(path[i-1][0], path[i-1][1], motionOf(path[i][0]-path[i-1][0], path[i][1]-path[i-1][1]))
+				cost += mc
+			}
+			s.AddEdge(RoadEdge{From: u, To: t, Cost: cost, Clearance: pathClearance(clr, a.Width, path), Path: path})
+			u = t
+		}
+	}
+	return s
+}
+
+// mainParts returns roadmap with the largest connected part of the graph in each free space
+func (r *Roadmap) mainParts(a *Astar) *Roadmap {
+	// free space (8-connected traversable cells)
+	space := make([]int32, a.Width*a.Height)
+	for i := range space {
+		space[i] = -1
+	}
+	var ns int32
+	for _, n := range r.Nodes {
+		if space[n.Y*a.Width+n.X] >= 0 {
+			continue
+		}
+		stack := []int{n.Y*a.Width + n.X}
+		space[stack[0]] = ns
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, m := range motion {
+				x, y := c%a.Width+int(m[0]), c/a.Width+int(m[1])
+				if x < 0 || y < 0 || x > a.MaxX || y > a.MaxY || !a.verifyPoint(x, y) {
+					continue
+				}
+				if j := y*a.Width + x; space[j] < 0 {
+					space[j] = ns
+					stack = append(stack, j)
+				}
+			}
+		}
+		ns++
+	}
+	// connected parts of the graph (ignoring direction)
+	part := make([]int, len(r.Nodes))
+	for i := range part {
+		part[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if part[i] != i {
+			part[i] = find(part[i])
+		}
+		return part[i]
+	}
+	for _, e := range r.Edges {
+		part[find(e.From)] = find(e.To)
+	}
+	size := make(map[int]int)
+	for i := range r.Nodes {
+		size[find(i)]++
+	}
+	main := make(map[int32]int) // free space -> largest part
+	for i, n := range r.Nodes {
+		s, p := space[n.Y*a.Width+n.X], find(i)
+		if m, ok := main[s]; !ok || size[p] > size[m] || size[p] == size[m] && p < m {
+			main[s] = p
+		}
+	}
+	keep := &Roadmap{Width: r.Width, Height: r.Height, Version: r.Version}
+	id := make([]int, len(r.Nodes))
+	for i, n := range r.Nodes {
+		id[i] = -1
+		if main[space[n.Y*a.Width+n.X]] == find(i) {
+			id[i] = keep.AddNode(n)
+		}
+	}
+	for _, e := range r.Edges {
+		if id[e.From] >= 0 {
+			e.From, e.To = id[e.From], id[e.To]
+			keep.AddEdge(e)
+		}
+	}
+	return keep
+}
+
+// pathClearance returns min clearance of cells on the path
+func pathClearance(clr []float64, width int, path [][2]int) float64 {
+	m := math.Inf(1)
+	for _, p := range path {
+		m = math.Min(m, clr[p[1]*width+p[0]])
+	}
+	return m
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package astar_wr
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// roadmapComponents returns number of weakly connected components of the roadmap
+func roadmapComponents(r *Roadmap) int {
+	label := make([]int, len(r.Nodes))
+	for i := range label {
+		label[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if label[i] != i {
+			label[i] = find(label[i])
+		}
+		return label[i]
+	}
+	n := len(r.Nodes)
+	for _, e := range r.Edges {
+		if a, b := find(e.From), find(e.To); a != b {
+			label[a] = b
+			n--
+		}
+	}
+	return n
+}
+
+func TestExtractRoadmap(t *testing.T) {
+	a := shelfMap()
+	r, err := ExtractRoadmap(a, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := roadmapComponents(r); n != 1 {
+		t.Errorf("roadmap has %d components", n)
+	}
+	v := VoronoiRoadmap(a, nil)
+	for i, n := range r.Nodes {
+		if !v.IsLane(n.X, n.Y) {
+			t.Errorf("node %d (%d, %d) is not on the skeleton", i, n.X, n.Y)
+		}
+	}
+	for i, e := range r.Edges {
+		if len(e.Path) > 6+1 {
+			t.Errorf("edge %d has %d cells", i, len(e.Path))
+		}
+		from, to := r.Nodes[e.From], r.Nodes[e.To]
+		if e.Path[0] != [2]int{from.X, from.Y} || e.Path[len(e.Path)-1] != [2]int{to.X, to.Y} {
+			t.Errorf("edge %d path %v-%v, nodes %d-%d", i, e.Path[0], e.Path[len(e.Path)-1], e.From, e.To)
+		}
+		// Plan format of the path
+		route := make([][2]int, len(e.Path))
+		for j, p := range e.Path {
+			route[len(e.Path)-1-j] = p
+		}
+		if c, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(c-e.Cost) > 1e-9 {
+			t.Errorf("edge %d cost %f, route cost %f (blocked %d)", i, e.Cost, c, blocked)
+		}
+		// reverse edge is split at the same cells
+		reverse := false
+		for _, j := range r.Out(e.To) {
+			if r.Edges[j].To == e.From && reflect.DeepEqual(r.Edges[j].Path, route) {
+				reverse = true
+			}
+		}
+		if !reverse {
+			t.Errorf("edge %d %d->%d has no reverse edge", i, e.From, e.To)
+		}
+	}
+}
+
+func TestRoadmapNearest(t *testing.T) {
+	r, err := ExtractRoadmap(shelfMap(), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := -5; x < 65; x++ {
+		for y := -5; y < 45; y++ {
+			best, bi := math.Inf(1), -1
+			for i, n := range r.Nodes {
+				if d := math.Hypot(float64(n.X-x), float64(n.Y-y)); d < best {
+					best, bi = d, i
+				}
+			}
+			if i := r.Nearest(x, y); i != bi {
+				t.Fatalf("Nearest(%d, %d) = %d, want %d", x, y, i, bi)
+			}
+		}
+	}
+	in := r.NodesIn(10, 2, 30, 22)
+	count := 0
+	for _, n := range r.Nodes {
+		if n.X >= 10 && n.X <= 30 && n.Y >= 2 && n.Y <= 22 {
+			count++
+		}
+	}
+	if len(in) != count {
+		t.Errorf("NodesIn returns %d nodes, want %d", len(in), count)
+	}
+}
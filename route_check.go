@@ -0,0 +1,114 @@
+package astar_wr
+
+import "math"
+
+// Route validity check against updated maps
+
+// RouteStatus is the result of CheckRoute
+type RouteStatus struct {
+	Blocked      bool
+	BlockedIndex int    // segment route[BlockedIndex] -> route[BlockedIndex-1] is blocked (-1: not blocked)
+	BlockedCell  [2]int // first blocked cell on the segment
+	OriginalCost float64
+	Cost         float64 // current cost (Inf if blocked)
+	Version      uint64  // map version of the check
+	Replan       bool    // replanning is recommended
+}
+
+func sign(v int) int {
+	if v > 0 {
+		return 1
+	} else if v < 0 {
+		return -1
+	}
+	return 0
+}
+
+// motionOf returns motion index of unit move (dx,dy)
+func motionOf(dx, dy int) int {
+	for k, v := range motion {
+		if int(v[0]) == dx && int(v[1]) == dy {
+			return k
+		}
+	}
+	return -1
+}
+
+// segmentCost walks from p to q (diagonal first, as RouteOptimization output) and sums move costs.
+// blocked cell is returned if the walk is not allowed.
+func (a *Astar) segmentCost(p, q [2]int) (float64, [2]int, bool) {
+	cost := 0.0
+	x, y := p[0], p[1]
+	for x != q[0] || y != q[1] {
+		dx, dy := sign(q[0]-x), sign(q[1]-y)
+		nx, ny := x+dx, y+dy
+		if !a.verifyPoint(nx, ny) {
+			return math.Inf(1), [2]int{nx, ny}, false
+		}
+		c, ok := a.moveCost(x, y, motionOf(dx, dy))
+		if !ok {
+			return math.Inf(1), [2]int{nx, ny}, false
+		}
+		cost += c
+		x, y = nx, ny
+	}
+	return cost, [2]int{}, true
+}
+
+// RouteCost returns cost of the route (Plan format, goal first) on the current map,
+// same cost as Plan. optimized routes (RouteOptimization) are also accepted.
+// blocked is the index of first blocked segment (-1: not blocked).
+func (a *Astar) RouteCost(route [][2]int) (cost float64, blocked int) {
+	blocked = -1
+	if len(route) == 0 {
+		return 0, blocked
+	}
+	if !a.verifyPoint(route[len(route)-1][0], route[len(route)-1][1]) {
+		return math.Inf(1), len(route) - 1
+	}
+	for i := len(route) - 1; i > 0; i-- {
+		c, _, ok := a.segmentCost(route[i], route[i-1])
+		if !ok {
+			return math.Inf(1), i
+		}
+		cost += c
+	}
+	return cost, blocked
+}
+
+// CheckRoute checks the route (dispatched with originalCost) on the current map.
+// replanning is recommended when blocked or cost increased more than tolerance (e.g. 0.2 = 20%).
+func (a *Astar) CheckRoute(route [][2]int, originalCost, tolerance float64) RouteStatus {
+	st := RouteStatus{
+		BlockedIndex: -1,
+		OriginalCost: originalCost,
+		Version:      a.Version,
+	}
+	st.Cost, st.BlockedIndex = a.RouteCost(route)
+	if st.BlockedIndex >= 0 {
+		st.Blocked = true
+		st.Replan = true
+		i := st.BlockedIndex
+		if i == len(route)-1 && !a.verifyPoint(route[i][0], route[i][1]) {
+			st.BlockedCell = route[i] // start itself
+		} else {
+			_, st.BlockedCell, _ = a.segmentCost(route[i], route[i-1])
+		}
+		return st
+	}
+	st.Replan = st.Cost > originalCost*(1+tolerance)
+	return st
+}
+
+// CheckRoutes checks many routes at once, originalCosts[i] is the cost of routes[i].
+func (a *Astar) CheckRoutes(routes [][][2]int, originalCosts []float64, tolerance float64) []RouteStatus {
+	sts := make([]RouteStatus, len(routes))
+	for i, rt := range routes {
+		orig := math.Inf(1)
+		if i < len(originalCosts) {
+			orig = originalCosts[i]
+		}
+		sts[i] = a.CheckRoute(rt, orig, tolerance)
+	}
+	return sts
+}
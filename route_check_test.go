@@ -0,0 +1,53 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckRoute(t *testing.T) {
+	a := NewWeightedAstar(nil, 20, 10, 0)
+	route, err := a.Plan(0, 5, 19, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, blocked := a.RouteCost(route)
+	if blocked >= 0 || cost != 19 {
+		t.Fatalf("RouteCost = %f (blocked %d), want 19", cost, blocked)
+	}
+	opt := [][2]int{{19, 5}, {0, 5}} // RouteOptimization output
+	if c, _ := a.RouteCost(opt); c != cost {
+		t.Errorf("optimized route cost %f, want %f", c, cost)
+	}
+	if st := a.CheckRoute(route, cost, 0.2); st.Blocked || st.Replan || st.BlockedIndex != -1 {
+		t.Errorf("free route: %+v", st)
+	}
+
+	a.SetCost(10, 5, 20) // 19 -> 39
+	if st := a.CheckRoute(route, cost, 0.2); st.Blocked || !st.Replan || st.Cost != 39 || st.Version != a.Version {
+		t.Errorf("expensive route: %+v", st)
+	}
+	if st := a.CheckRoute(route, cost, 2); st.Replan {
+		t.Errorf("cost within tolerance: %+v", st)
+	}
+
+	a.SetCost(10, 5, 0xff)
+	// route[0] is the goal, segment route[10]->route[9] enters (10,5)
+	st := a.CheckRoute(route, cost, 0.2)
+	if !st.Blocked || !st.Replan || st.BlockedIndex != 10 || st.BlockedCell != [2]int{10, 5} || !math.IsInf(st.Cost, 1) {
+		t.Errorf("blocked route: %+v", st)
+	}
+	st = a.CheckRoute(opt, cost, 0.2)
+	if st.BlockedIndex != 1 || st.BlockedCell != [2]int{10, 5} {
+		t.Errorf("blocked optimized route: %+v", st)
+	}
+
+	a.SetCost(0, 5, 0xff) // start
+	sts := a.CheckRoutes([][][2]int{route, {{5, 2}, {3, 2}}}, []float64{cost}, 0.2)
+	if sts[0].BlockedIndex != len(route)-1 || sts[0].BlockedCell != [2]int{0, 5} {
+		t.Errorf("blocked start: %+v", sts[0])
+	}
+	if sts[1].Blocked || sts[1].Replan || sts[1].Cost != 2 {
+		t.Errorf("route without original cost: %+v", sts[1])
+	}
+}
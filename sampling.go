@@ -0,0 +1,354 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Sampling based planners (PRM, RRT*) on the Astar cost map
+// a segment between samples is walked diagonal first (same as RouteOptimization output),
+// its cost is the sum of move costs of the cells (segmentCost).
+
+// segmentCells returns cells from p (excluded) to q (included) in driving order
+func segmentCells(p, q [2]int) [][2]int {
+	var cells [][2]int
+	x, y := p[0], p[1]
+	for x != q[0] || y != q[1] {
+		x, y = x+sign(q[0]-x), y+sign(q[1]-y)
+		cells = append(cells, [2]int{x, y})
+	}
+	return cells
+}
+
+// pointsRoute expands points in driving order into cells in Plan format (goal first)
+func pointsRoute(points [][2]int) [][2]int {
+	fwd := [][2]int{points[0]}
+	for i := 1; i < len(points); i++ {
+		fwd = append(fwd, segmentCells(points[i-1], points[i])...)
+	}
+	route := make([][2]int, len(fwd))
+	for i, p := range fwd {
+		route[len(fwd)-1-i] = p
+	}
+	return route
+}
+
+// samplePoint returns random traversable cell (ok is false if not found)
+func (a *Astar) samplePoint(rnd *rand.Rand) ([2]int, bool) {
+	for i := 0; i < 1000; i++ {
+		x, y := rnd.Intn(a.Width), rnd.Intn(a.Height)
+		if a.verifyPoint(x, y) {
+			return [2]int{x, y}, true
+		}
+	}
+	return [2]int{}, false
+}
+
+func dist2(p, q [2]int) float64 {
+	return math.Hypot(float64(p[0]-q[0]), float64(p[1]-q[1]))
+}
+
+// PRM is probabilistic roadmap with k-nearest connections
+type PRM struct {
+	Astar   *Astar
+	K       int
+	Roadmap *Roadmap
+}
+
+// NewPRM samples nodes and connects each node to k nearest nodes (same seed, same roadmap)
+func NewPRM(a *Astar, samples, k int, seed int64) *PRM {
+	rnd := rand.New(rand.NewSource(seed))
+	p := &PRM{Astar: a, K: k, Roadmap: &Roadmap{Width: a.Width, Height: a.Height, Version: a.Version}}
+	r := p.Roadmap
+	for i := 0; i < samples; i++ {
+		pt, ok := a.samplePoint(rnd)
+		if !ok {
+			break
+		}
+		r.AddNode(RoadNode{X: pt[0], Y: pt[1]})
+	}
+	connected := make(map[[2]int]bool)
+	for u := range r.Nodes {
+		for _, v := range p.nearest(p.pos(u), u) {
+			if connected[[2]int{u, v}] {
+				continue
+			}
+			connected[[2]int{u, v}] = true
+			connected[[2]int{v, u}] = true
+			p.connect(u, v)
+			p.connect(v, u)
+		}
+	}
+	return p
+}
+
+func (p *PRM) pos(u int) [2]int {
+	return [2]int{p.Roadmap.Nodes[u].X, p.Roadmap.Nodes[u].Y}
+}
+
+// nearest returns K nearest nodes of pt (except node skip)
+func (p *PRM) nearest(pt [2]int, skip int) []int {
+	var near []int
+	var ds []float64
+	for v := range p.Roadmap.Nodes {
+		if v == skip {
+			continue
+		}
+		d := dist2(pt, p.pos(v))
+		i := len(near)
+		for i > 0 && ds[i-1] > d {
+			i--
+		}
+		if i >= p.K {
+			continue
+		}
+		near = append(near[:i], append([]int{v}, near[i:]...)...)
+		ds = append(ds[:i], append([]float64{d}, ds[i:]...)...)
+		if len(near) > p.K {
+			near, ds = near[:p.K], ds[:p.K]
+		}
+	}
+	return near
+}
+
+func (p *PRM) connect(u, v int) {
+	pu, pv := p.pos(u), p.pos(v)
+	if c, _, ok := p.Astar.segmentCost(pu, pv); ok {
+		path := append([][2]int{pu}, segmentCells(pu, pv)...)
+		p.Roadmap.AddEdge(RoadEdge{From: u, To: v, Cost: c, Path: path})
+	}
+}
+
+// Plan connects start and goal to K nearest nodes and searches the roadmap by A*.
+// route is in Plan format with its cost.
+func (p *PRM) Plan(sx, sy, gx, gy int) ([][2]int, float64, error) {
+	a := p.Astar
+	if !a.verifyPoint(sx, sy) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	r := p.Roadmap
+	s, g := [2]int{sx, sy}, [2]int{gx, gy}
+	n := len(r.Nodes)
+	sid, gid := n, n+1 // temporary nodes
+	pos := func(u int) [2]int {
+		switch u {
+		case sid:
+			return s
+		case gid:
+			return g
+		}
+		return p.pos(u)
+	}
+	type edge struct {
+		to   int
+		cost float64
+	}
+	startEdges := []edge{}
+	if c, _, ok := a.segmentCost(s, g); ok {
+		startEdges = append(startEdges, edge{gid, c})
+	}
+	for _, v := range p.nearest(s, -1) {
+		if c, _, ok := a.segmentCost(s, p.pos(v)); ok {
+			startEdges = append(startEdges, edge{v, c})
+		}
+	}
+	goalEdges := make(map[int]float64)
+	for _, v := range p.nearest(g, -1) {
+		if c, _, ok := a.segmentCost(p.pos(v), g); ok {
+			goalEdges[v] = c
+		}
+	}
+	cost := map[int]float64{sid: 0}
+	prev := map[int]int{sid: -1}
+	closed := make(map[int]bool)
+	pq := &priorityQueue{}
+	pq.push(sid, dist2(s, g))
+	for pq.Len() > 0 {
+		u := pq.pop().key
+		if closed[u] {
+			continue
+		}
+		closed[u] = true
+		if u == gid {
+			break
+		}
+		var es []edge
+		if u == sid {
+			es = startEdges
+		} else {
+			for _, ei := range r.Out(u) {
+				es = append(es, edge{r.Edges[ei].To, r.Edges[ei].Cost})
+			}
+			if c, ok := goalEdges[u]; ok {
+				es = append(es, edge{gid, c})
+			}
+		}
+		for _, e := range es {
+			nc := cost[u] + e.cost
+			if old, ok := cost[e.to]; closed[e.to] || ok && old <= nc {
+				continue
+			}
+			cost[e.to] = nc
+			prev[e.to] = u
+			pq.push(e.to, nc+dist2(pos(e.to), g))
+		}
+	}
+	if !closed[gid] {
+		return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): not connected in the roadmap", sx, sy, gx, gy)
+	}
+	var points [][2]int
+	for u := gid; u != -1; u = prev[u] {
+		points = append([][2]int{pos(u)}, points...)
+	}
+	route := pointsRoute(points)
+	return route, cost[gid], a.checkUnknown(route)
+}
+
+// RRTStar planner, parameters can be changed after NewRRTStar
+type RRTStar struct {
+	Astar      *Astar
+	Iterations int
+	StepSize   float64 // max length of new edge [cell]
+	Gamma      float64 // rewiring radius is Gamma*sqrt(log(n)/n) (up to StepSize)
+	GoalBias   float64 // probability of sampling the goal
+	Seed       int64
+}
+
+// NewRRTStar returns planner with default parameters
+func NewRRTStar(a *Astar, seed int64) *RRTStar {
+	return &RRTStar{
+		Astar:      a,
+		Iterations: 5000,
+		StepSize:   20,
+		Gamma:      60,
+		GoalBias:   0.05,
+		Seed:       seed,
+	}
+}
+
+type rrtNode struct {
+	pt       [2]int
+	parent   int
+	cost     float64
+	children []int
+}
+
+// Plan grows the tree for Iterations and returns the best route to the goal (Plan format) with its cost.
+func (rs *RRTStar) Plan(sx, sy, gx, gy int) ([][2]int, float64, error) {
+	a := rs.Astar
+	if !a.verifyPoint(sx, sy) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	rnd := rand.New(rand.NewSource(rs.Seed))
+	g := [2]int{gx, gy}
+	tree := []*rrtNode{{pt: [2]int{sx, sy}, parent: -1}}
+	goalLinks := make(map[int]float64) // node -> cost to goal
+	if c, _, ok := a.segmentCost(tree[0].pt, g); ok {
+		goalLinks[0] = c
+	}
+	// propagate cost change to descendants
+	var update func(i int, delta float64)
+	update = func(i int, delta float64) {
+		tree[i].cost += delta
+		for _, c := range tree[i].children {
+			update(c, delta)
+		}
+	}
+	for it := 0; it < rs.Iterations; it++ {
+		q := g
+		if rnd.Float64() >= rs.GoalBias {
+			var ok bool
+			if q, ok = a.samplePoint(rnd); !ok {
+				break
+			}
+		}
+		// nearest
+		ni, nd := 0, math.Inf(1)
+		for i, n := range tree {
+			if d := dist2(n.pt, q); d < nd {
+				ni, nd = i, d
+			}
+		}
+		if nd == 0 {
+			continue
+		}
+		// steer
+		from := tree[ni].pt
+		if nd > rs.StepSize {
+			f := rs.StepSize / nd
+			q = [2]int{from[0] + int(math.Round(float64(q[0]-from[0])*f)), from[1] + int(math.Round(float64(q[1]-from[1])*f))}
+		}
+		if !a.verifyPoint(q[0], q[1]) {
+			continue
+		}
+		// choose parent in near nodes
+		n := float64(len(tree) + 1)
+		radius := math.Min(rs.Gamma*math.Sqrt(math.Log(n)/n), rs.StepSize)
+		var near []int
+		for i, t := range tree {
+			if d := dist2(t.pt, q); d <= radius || i == ni {
+				near = append(near, i)
+			}
+		}
+		best, bc := -1, math.Inf(1)
+		for _, i := range near {
+			if c, _, ok := a.segmentCost(tree[i].pt, q); ok && tree[i].cost+c < bc {
+				best, bc = i, tree[i].cost+c
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		id := len(tree)
+		tree = append(tree, &rrtNode{pt: q, parent: best, cost: bc})
+		tree[best].children = append(tree[best].children, id)
+		// rewire
+		for _, i := range near {
+			if i == best || i == 0 {
+				continue
+			}
+			c, _, ok := a.segmentCost(q, tree[i].pt)
+			if !ok || bc+c >= tree[i].cost {
+				continue
+			}
+			p := tree[tree[i].parent]
+			for k, ch := range p.children {
+				if ch == i {
+					p.children = append(p.children[:k], p.children[k+1:]...)
+					break
+				}
+			}
+			tree[i].parent = id
+			tree[id].children = append(tree[id].children, i)
+			update(i, bc+c-tree[i].cost)
+		}
+		if c, _, ok := a.segmentCost(q, g); ok {
+			goalLinks[id] = c
+		}
+	}
+	bi, bc := -1, math.Inf(1)
+	for i, c := range goalLinks {
+		if tree[i].cost+c < bc || tree[i].cost+c == bc && i < bi {
+			bi, bc = i, tree[i].cost+c
+		}
+	}
+	if bi < 0 {
+		return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): goal is not reached in %d iterations", sx, sy, gx, gy, rs.Iterations)
+	}
+	points := [][2]int{g}
+	if tree[bi].pt == g {
+		points = nil
+	}
+	for i := bi; i != -1; i = tree[i].parent {
+		points = append([][2]int{tree[i].pt}, points...)
+	}
+	route := pointsRoute(points)
+	return route, bc, a.checkUnknown(route)
+}
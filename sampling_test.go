@@ -0,0 +1,89 @@
+package astar_wr
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// samplingPlanner is PRM or RRTStar
+type samplingPlanner interface {
+	Plan(sx, sy, gx, gy int) ([][2]int, float64, error)
+}
+
+func samplingPlanners(a *Astar, seed int64) map[string]samplingPlanner {
+	rs := NewRRTStar(a, seed)
+	rs.Iterations = 2000
+	return map[string]samplingPlanner{"PRM": NewPRM(a, 400, 10, seed), "RRTStar": rs}
+}
+
+func checkSampling(t *testing.T, a *Astar, name string, route [][2]int, cost float64, sx, sy, gx, gy int) {
+	t.Helper()
+	if route[0] != [2]int{gx, gy} || route[len(route)-1] != [2]int{sx, sy} {
+		t.Errorf("%s: route from %v to %v", name, route[len(route)-1], route[0])
+	}
+	if c, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(c-cost) > 1e-9 {
+		t.Errorf("%s: route cost %f (blocked %d), cost %f", name, c, blocked, cost)
+	}
+	f, _ := a.Dijkstra(sx, sy)
+	if opt := f.Cost(gx, gy); cost < opt-1e-9 {
+		t.Errorf("%s: cost %f is less than optimal %f", name, cost, opt)
+	}
+}
+
+func TestSamplingPlanners(t *testing.T) {
+	a := shelfMap()
+	cases := [][4]int{{2, 2, 57, 37}, {12, 30, 28, 10}, {3, 20, 5, 22}}
+	for name, p := range samplingPlanners(a, 1) {
+		again := samplingPlanners(a, 1)[name]
+		for _, c := range cases {
+			route, cost, err := p.Plan(c[0], c[1], c[2], c[3])
+			if err != nil {
+				t.Fatalf("%s Plan%v: %v", name, c, err)
+			}
+			checkSampling(t, a, name, route, cost, c[0], c[1], c[2], c[3])
+			// same seed, same route
+			r2, c2, err := again.Plan(c[0], c[1], c[2], c[3])
+			if err != nil || c2 != cost || !reflect.DeepEqual(r2, route) {
+				t.Errorf("%s Plan%v: cost %f (%v) with the same seed, want %f", name, c, c2, err, cost)
+			}
+		}
+	}
+}
+
+func TestSamplingOneWay(t *testing.T) {
+	a := shelfMap()
+	randomOneWay(a, 0.1, 3)
+	for name, p := range samplingPlanners(a, 2) {
+		for _, c := range [][4]int{{2, 2, 57, 37}, {57, 37, 2, 2}} {
+			route, cost, err := p.Plan(c[0], c[1], c[2], c[3])
+			if err != nil {
+				t.Errorf("%s Plan%v: %v", name, c, err)
+				continue
+			}
+			// RouteCost rejects wrong-way moves
+			checkSampling(t, a, name, route, cost, c[0], c[1], c[2], c[3])
+		}
+	}
+}
+
+func TestSamplingUnreachable(t *testing.T) {
+	a := shelfMap()
+	// closed box around (55,35)
+	var box [][2]int
+	for i := 52; i <= 58; i++ {
+		box = append(box, [2]int{i, 32}, [2]int{i, 38}, [2]int{52, i - 20}, [2]int{58, i - 20})
+	}
+	a.AddObstacles(box)
+	if !a.verifyPoint(55, 35) {
+		t.Fatal("goal in the box is not traversable")
+	}
+	for name, p := range samplingPlanners(a, 1) {
+		if _, _, err := p.Plan(2, 2, 55, 35); err == nil {
+			t.Errorf("%s: goal in a closed box is reached", name)
+		}
+		if _, _, err := p.Plan(2, 2, 8, 10); err == nil {
+			t.Errorf("%s: goal on a shelf is accepted", name)
+		}
+	}
+}
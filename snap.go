@@ -0,0 +1,130 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Snapping start/goal to traversable cells and planning to the neighborhood of the goal
+
+// Snap is the adjustment of a point by SnapPoint
+type Snap struct {
+	From  [2]int
+	To    [2]int
+	Dist  float64 // Euclid distance of the adjustment
+	Moved bool
+}
+
+// SnapPoint returns the nearest traversable cell of (x,y) within maxRadius (Euclid),
+// searched by BFS over the grid from (x,y). (x,y) itself is returned if traversable.
+// (x,y) may be out of the map, the search is bounded by maxRadius only.
+func (a *Astar) SnapPoint(x, y int, maxRadius float64) (Snap, error) {
+	s := Snap{From: [2]int{x, y}, To: [2]int{x, y}}
+	if a.verifyPoint(x, y) {
+		return s, nil
+	}
+	r := int(math.Ceil(maxRadius))
+	visited := make(map[[2]int]bool)
+	queue := [][2]int{{x, y}}
+	visited[[2]int{x, y}] = true
+	best := math.Inf(1)
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		// BFS layer is the Chebyshev distance, Euclid distance is not smaller than it
+		if float64(abs(c[0]-x)) > best || float64(abs(c[1]-y)) > best {
+			break
+		}
+		if d := math.Hypot(float64(c[0]-x), float64(c[1]-y)); d < best && a.verifyPoint(c[0], c[1]) {
+			best = d
+			s.To = c
+		}
+		for _, v := range motion {
+			n := [2]int{c[0] + int(v[0]), c[1] + int(v[1])}
+			if visited[n] || abs(n[0]-x) > r || abs(n[1]-y) > r || math.Hypot(float64(n[0]-x), float64(n[1]-y)) > maxRadius {
+				continue
+			}
+			visited[n] = true
+			queue = append(queue, n)
+		}
+	}
+	if math.IsInf(best, 1) {
+		return s, fmt.Errorf("no traversable cell within %.1f of (%d, %d)", maxRadius, x, y)
+	}
+	s.Dist = best
+	s.Moved = true
+	return s, nil
+}
+
+// PlanSnapped is Plan with start and goal snapped to traversable cells within snapRadius.
+// the adjustments are returned with the route.
+func (a *Astar) PlanSnapped(sx, sy, gx, gy int, weight, snapRadius float64) (route [][2]int, start, goal Snap, err error) {
+	if start, err = a.SnapPoint(sx, sy, snapRadius); err != nil {
+		return nil, start, goal, fmt.Errorf("start: %v", err)
+	}
+	if goal, err = a.SnapPoint(gx, gy, snapRadius); err != nil {
+		return nil, start, goal, fmt.Errorf("goal: %v", err)
+	}
+	route, err = a.Plan(start.To[0], start.To[1], goal.To[0], goal.To[1], weight)
+	return route, start, goal, err
+}
+
+// PlanNear plans route from (sx,sy) to the cheapest cell within Euclid distance r of (gx,gy).
+// (gx,gy) itself may be blocked. route[0] is the reached cell.
+func (a *Astar) PlanNear(sx, sy, gx, gy int, weight, r float64) ([][2]int, error) {
+	return a.PlanToRegion(sx, sy, GoalCircle{gx, gy, r}, weight)
+}
+
+// planGoal is A* search from (sx,sy) to the first cell satisfying goal, h is heuristic.
+func (a *Astar) planGoal(sx, sy int, goal func(x, y int) bool, h func(x, y int) float64) ([][2]int, error) {
+	if !a.verifyPoint(sx, sy) {
+		return nil, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	start := sy*a.Width + sx
+	cost := map[int]float64{start: 0}
+	prev := map[int]int{start: -1}
+	closed := make(map[int]bool)
+	pq := &priorityQueue{}
+	pq.push(start, h(sx, sy))
+	for pq.Len() > 0 {
+		c := pq.pop().key
+		if closed[c] {
+			continue
+		}
+		closed[c] = true
+		cx, cy := a.indToPosXY(c)
+		if goal(cx, cy) {
+			var route [][2]int
+			for i := c; i != -1; i = prev[i] {
+				x, y := a.indToPosXY(i)
+				route = append(route, [2]int{x, y})
+			}
+			return route, a.checkUnknown(route)
+		}
+		for k, v := range motion {
+			nx, ny := cx+int(v[0]), cy+int(v[1])
+			if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+				continue
+			}
+			mc, ok := a.moveCost(cx, cy, k)
+			if !ok {
+				continue
+			}
+			n := ny*a.Width + nx
+			if old, ok := cost[n]; closed[n] || ok && old <= cost[c]+mc {
+				continue
+			}
+			cost[n] = cost[c] + mc
+			prev[n] = c
+			pq.push(n, cost[n]+h(nx, ny))
+		}
+	}
+	return nil, fmt.Errorf("fail searching point from (%d,%d): goal is not reachable", sx, sy)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSnapPoint(t *testing.T) {
+	var objects [][2]int
+	for x := 3; x < 7; x++ {
+		for y := 3; y < 7; y++ {
+			objects = append(objects, [2]int{x, y}) // 4x4 block
+		}
+	}
+	a := NewWeightedAstar(objects, 10, 10, 0)
+	cases := []struct {
+		x, y   int
+		r      float64
+		to     [2]int
+		moved  bool
+		failed bool
+	}{
+		{1, 1, 2, [2]int{1, 1}, false, false},  // free
+		{4, 3, 2, [2]int{4, 2}, true, false},   // inside the block
+		{5, 5, 1.5, [2]int{}, false, true},     // radius exceeded
+		{12, 5, 4, [2]int{9, 5}, true, false},  // right of the map
+		{-2, -2, 3, [2]int{0, 0}, true, false}, // above left of the map
+		{15, 5, 4, [2]int{}, false, true},      // map is too far
+		{5, 11, 2, [2]int{5, 9}, true, false},  // below the map
+	}
+	for _, c := range cases {
+		s, err := a.SnapPoint(c.x, c.y, c.r)
+		if c.failed {
+			if err == nil {
+				t.Errorf("SnapPoint(%d, %d, %.1f) = %v, want error", c.x, c.y, c.r, s.To)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SnapPoint(%d, %d, %.1f): %v", c.x, c.y, c.r, err)
+			continue
+		}
+		d := math.Hypot(float64(c.to[0]-c.x), float64(c.to[1]-c.y))
+		if s.To != c.to || s.Moved != c.moved || s.From != [2]int{c.x, c.y} || math.Abs(s.Dist-d) > 1e-9 {
+			t.Errorf("SnapPoint(%d, %d, %.1f) = %+v, want %v", c.x, c.y, c.r, s, c.to)
+		}
+	}
+}
+
+func TestPlanSnapped(t *testing.T) {
+	a := shelfMap()
+	// start inside a shelf, goal out of the map
+	route, start, goal, err := a.PlanSnapped(8, 20, 63, 20, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Moved || !goal.Moved || goal.To != [2]int{59, 20} || math.Abs(goal.Dist-4) > 1e-9 {
+		t.Errorf("start %+v, goal %+v", start, goal)
+	}
+	if start.To != [2]int{7, 20} && start.To != [2]int{10, 20} {
+		t.Errorf("start is snapped to %v", start.To)
+	}
+	if route[0] != goal.To || route[len(route)-1] != start.To {
+		t.Errorf("route from %v to %v", route[len(route)-1], route[0])
+	}
+	if _, _, _, err := a.PlanSnapped(2, 2, 70, 20, 1, 4); err == nil {
+		t.Error("goal out of snap radius is accepted")
+	}
+	if _, _, _, err := a.PlanSnapped(-9, 20, 30, 20, 1, 4); err == nil {
+		t.Error("start out of snap radius is accepted")
+	}
+}
+
+func TestPlanNear(t *testing.T) {
+	a := shelfMap()
+	route, err := a.PlanNear(2, 20, 8, 20, 1, 2) // goal on the shelf
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route[0] != [2]int{6, 20} || route[len(route)-1] != [2]int{2, 20} {
+		t.Errorf("route from %v to %v", route[len(route)-1], route[0])
+	}
+	// goal out of the map, cells within r are in the map
+	route, err = a.PlanNear(50, 20, 62, 20, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route[0] != [2]int{59, 20} {
+		t.Errorf("route reaches %v", route[0])
+	}
+	if _, err := a.PlanNear(2, 20, 8, 20, 1, 0.5); err == nil {
+		t.Error("blocked goal without free cell within r is reached")
+	}
+}
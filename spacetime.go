@@ -0,0 +1,169 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Space-time A* (x, y, t) on the weighted grid, used by multi-agent planners.
+// each move (or wait) takes one time step.
+
+// TimedRoute is a route in time, TimedRoute[t] is the cell at time t.
+// unlike Plan, start is first and waits are repeated cells.
+type TimedRoute [][2]int
+
+// At returns the cell at time t (stays at the last cell after arrival)
+func (tr TimedRoute) At(t int) [2]int {
+	if t >= len(tr) {
+		return tr[len(tr)-1]
+	}
+	return tr[t]
+}
+
+// Route converts into Plan format (goal first, without waits)
+func (tr TimedRoute) Route() (route [][2]int) {
+	for i := len(tr) - 1; i >= 0; i-- {
+		if len(route) > 0 && route[len(route)-1] == tr[i] {
+			continue
+		}
+		route = append(route, tr[i])
+	}
+	return route
+}
+
+// spaceTimeConstraint tells the search which (cell, time) can not be used
+type spaceTimeConstraint interface {
+	// vertexBlocked is true if the agent can not be at (x,y) at time t
+	vertexBlocked(x, y, t int) bool
+	// edgeBlocked is true if the agent can not move (x,y)->(nx,ny) from time t to t+1
+	edgeBlocked(x, y, nx, ny, t int) bool
+	// lastBlocked returns the last time (x,y) is blocked (-1: never), the agent can stay at goal after that
+	lastBlocked(x, y int) int
+}
+
+type stNode struct {
+	x, y, t int
+	cost    float64
+	parent  int
+}
+
+// spaceTimePlan searches timed route from (sx,sy) at time 0 to (gx,gy) where the agent can stay.
+// cost of waiting is waitCost per step, h is cost-to-go (nil: Euclid distance).
+func (a *Astar) spaceTimePlan(sx, sy, gx, gy int, weight, waitCost float64, maxTime int, c spaceTimeConstraint, h []float64) (TimedRoute, float64, error) {
+	if !a.verifyPoint(sx, sy) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is not verified", sx, sy)
+	}
+	if !a.verifyPoint(gx, gy) {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is not verified", gx, gy)
+	}
+	if c.vertexBlocked(sx, sy, 0) {
+		return nil, 0, fmt.Errorf("start point (%d, %d) is blocked at time 0", sx, sy)
+	}
+	heur := func(x, y int) float64 {
+		if h != nil {
+			return weight * h[y*a.Width+x]
+		}
+		return weight * math.Hypot(float64(x-gx), float64(y-gy))
+	}
+	if math.IsInf(heur(sx, sy), 1) {
+		return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): goal is not reachable", sx, sy, gx, gy)
+	}
+	last := c.lastBlocked(gx, gy)
+	if last == parkForever {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is occupied forever", gx, gy)
+	}
+	if last >= maxTime {
+		return nil, 0, fmt.Errorf("goal point (%d, %d) is occupied until %d (max time %d)", gx, gy, last, maxTime)
+	}
+	goalFree := last + 1
+	size := a.Width * a.Height
+	key := func(x, y, t int) int { return t*size + y*a.Width + x }
+
+	nodes := make(map[int]*stNode)
+	closed := make(map[int]bool)
+	sKey := key(sx, sy, 0)
+	nodes[sKey] = &stNode{x: sx, y: sy, t: 0, parent: -1}
+	pq := &priorityQueue{}
+	pq.push(sKey, heur(sx, sy))
+
+	for pq.Len() > 0 {
+		cKey := pq.pop().key
+		if closed[cKey] {
+			continue
+		}
+		closed[cKey] = true
+		cur := nodes[cKey]
+		if cur.x == gx && cur.y == gy && cur.t >= goalFree {
+			tr := stFinalPath(nodes, cKey)
+			return tr, a.timedCost(tr, waitCost), nil
+		}
+		if cur.t >= maxTime {
+			continue
+		}
+		nt := cur.t + 1
+		// wait
+		if !c.vertexBlocked(cur.x, cur.y, nt) && !c.edgeBlocked(cur.x, cur.y, cur.x, cur.y, cur.t) {
+			// waiting on goal costs as elsewhere (same as timedCost), the search ends at goal after goalFree
+			stPush(nodes, closed, pq, key(cur.x, cur.y, nt), &stNode{x: cur.x, y: cur.y, t: nt, cost: cur.cost + waitCost, parent: cKey}, heur(cur.x, cur.y))
+		}
+		for k, v := range motion {
+			nx := cur.x + int(v[0])
+			ny := cur.y + int(v[1])
+			if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+				continue
+			}
+			mc, ok := a.moveCost(cur.x, cur.y, k)
+			if !ok || c.vertexBlocked(nx, ny, nt) || c.edgeBlocked(cur.x, cur.y, nx, ny, cur.t) {
+				continue
+			}
+			stPush(nodes, closed, pq, key(nx, ny, nt), &stNode{x: nx, y: ny, t: nt, cost: cur.cost + mc, parent: cKey}, heur(nx, ny))
+		}
+	}
+	return nil, 0, fmt.Errorf("fail searching point from (%d,%d) to (%d, %d): no route until time %d", sx, sy, gx, gy, maxTime)
+}
+
+func stPush(nodes map[int]*stNode, closed map[int]bool, pq *priorityQueue, k int, n *stNode, h float64) {
+	if closed[k] || math.IsInf(h, 1) {
+		return
+	}
+	if old, ok := nodes[k]; ok && old.cost <= n.cost {
+		return
+	}
+	nodes[k] = n
+	pq.push(k, n.cost+h)
+}
+
+func stFinalPath(nodes map[int]*stNode, k int) TimedRoute {
+	n := nodes[k]
+	tr := make(TimedRoute, n.t+1)
+	for k != -1 {
+		n = nodes[k]
+		tr[n.t] = [2]int{n.x, n.y}
+		k = n.parent
+	}
+	// remove waiting on goal at the end
+	for len(tr) > 1 && tr[len(tr)-1] == tr[len(tr)-2] {
+		tr = tr[:len(tr)-1]
+	}
+	return tr
+}
+
+// timedCost returns cost of the timed route (moves and waits)
+func (a *Astar) timedCost(tr TimedRoute, waitCost float64) float64 {
+	cost := 0.0
+	for t := 1; t < len(tr); t++ {
+		p, q := tr[t-1], tr[t]
+		if p == q {
+			cost += waitCost
+			continue
+		}
+		c, _ := a.moveCost(p[0], p[1], motionOf(q[0]-p[0], q[1]-p[1]))
+		cost += c
+	}
+	return cost
+}
+
+// costToGo computes cost from every cell to (gx,gy) by reverse Dijkstra (Inf if not reachable)
+func (a *Astar) costToGo(gx, gy int) []float64 {
+	return a.dijkstra([][2]int{{gx, gy}}, true).Dist
+}
@@ -0,0 +1,215 @@
+package astar_wr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Pick-tour optimization (TSP with fixed start and end depot)
+// distance matrix is made by reverse Dijkstra on the weighted map.
+
+// Tour is the result of OptimizeTour
+type Tour struct {
+	Order []int    // visiting order (index of stops)
+	Stops [][2]int // stops in visiting order
+	Legs  []Leg    // start -> stops... -> end
+	Route [][2]int // stitched route (Plan format, goal first)
+	Cost  float64
+}
+
+// tourCost is the cost of start -> order... -> end, nodes are 0: start, 1..n: stops, n+1: end
+func tourCost(d [][]float64, order []int) float64 {
+	n := len(order)
+	prev := 0
+	c := 0.0
+	for _, o := range order {
+		c += d[prev][o+1]
+		prev = o + 1
+	}
+	return c + d[prev][n+1]
+}
+
+func nearestNeighbor(d [][]float64, n int) []int {
+	order := make([]int, 0, n)
+	used := make([]bool, n)
+	prev := 0
+	for len(order) < n {
+		best, bi := math.Inf(1), -1
+		for i := 0; i < n; i++ {
+			if !used[i] && (bi < 0 || d[prev][i+1] < best) {
+				best, bi = d[prev][i+1], i
+			}
+		}
+		used[bi] = true
+		order = append(order, bi)
+		prev = bi + 1
+	}
+	return order
+}
+
+// improveTour applies 2-opt and Or-opt moves until no improvement (costs may be asymmetric)
+func improveTour(d [][]float64, order []int) []int {
+	n := len(order)
+	best := tourCost(d, order)
+	cand := make([]int, n)
+	for improved := true; improved; {
+		improved = false
+		// 2-opt: reverse order[i..j]
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				copy(cand, order)
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					cand[l], cand[r] = cand[r], cand[l]
+				}
+				if c := tourCost(d, cand); c < best-1e-9 {
+					best = c
+					copy(order, cand)
+					improved = true
+				}
+			}
+		}
+		// Or-opt: move segment of 1..3 stops to another position
+		for sl := 1; sl <= 3 && sl < n; sl++ {
+			for i := 0; i+sl <= n; i++ {
+				seg := append([]int(nil), order[i:i+sl]...)
+				rest := append(append([]int(nil), order[:i]...), order[i+sl:]...)
+				for p := 0; p <= len(rest); p++ {
+					if p == i {
+						continue
+					}
+					cand = cand[:0]
+					cand = append(cand, rest[:p]...)
+					cand = append(cand, seg...)
+					cand = append(cand, rest[p:]...)
+					if c := tourCost(d, cand); c < best-1e-9 {
+						best = c
+						copy(order, cand)
+						improved = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return order
+}
+
+// heldKarp solves the path TSP exactly by dynamic programming (n <= 16), nil if no tour
+func heldKarp(d [][]float64, n int) []int {
+	full := 1 << uint(n)
+	dp := make([][]float64, full)
+	par := make([][]int, full)
+	for s := range dp {
+		dp[s] = make([]float64, n)
+		par[s] = make([]int, n)
+		for i := range dp[s] {
+			dp[s][i] = math.Inf(1)
+			par[s][i] = -1
+		}
+	}
+	for i := 0; i < n; i++ {
+		dp[1<<uint(i)][i] = d[0][i+1]
+	}
+	for s := 1; s < full; s++ {
+		for i := 0; i < n; i++ {
+			if s&(1<<uint(i)) == 0 || math.IsInf(dp[s][i], 1) {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if s&(1<<uint(j)) != 0 {
+					continue
+				}
+				ns := s | 1<<uint(j)
+				if c := dp[s][i] + d[i+1][j+1]; c < dp[ns][j] {
+					dp[ns][j] = c
+					par[ns][j] = i
+				}
+			}
+		}
+	}
+	last, best := 0, math.Inf(1)
+	for i := 0; i < n; i++ {
+		if c := dp[full-1][i] + d[i+1][n+1]; c < best {
+			last, best = i, c
+		}
+	}
+	if math.IsInf(best, 1) {
+		return nil
+	}
+	order := make([]int, n)
+	s := full - 1
+	for k := n - 1; k >= 0; k-- {
+		order[k] = last
+		p := par[s][last]
+		s &^= 1 << uint(last)
+		last = p
+	}
+	return order
+}
+
+// OptimizeTour finds the visiting order of stops from start to end (start == end for round trip).
+// exact search (Held-Karp) is used if len(stops) <= exactLimit (max 16),
+// otherwise nearest neighbor + 2-opt / Or-opt.
+func (a *Astar) OptimizeTour(start, end [2]int, stops [][2]int, exactLimit int) (*Tour, error) {
+	nodes := append(append([][2]int{start}, stops...), end)
+	for i, p := range nodes {
+		if !a.verifyPoint(p[0], p[1]) {
+			return nil, fmt.Errorf("point %d (%d, %d) is not verified", i, p[0], p[1])
+		}
+	}
+	n := len(stops)
+	// d[i][j] is the cost from node i to node j (reverse field of j, kept for leg routes)
+	d := make([][]float64, n+2)
+	for i := range d {
+		d[i] = make([]float64, n+2)
+	}
+	fields := make([]*DistanceField, n+2)
+	for j := 1; j < n+2; j++ {
+		fields[j] = a.dijkstra([][2]int{nodes[j]}, true)
+		for i, p := range nodes {
+			d[i][j] = fields[j].Cost(p[0], p[1])
+		}
+	}
+	for j := 1; j <= n; j++ {
+		if math.IsInf(d[0][j], 1) {
+			return nil, fmt.Errorf("stop %d (%d, %d) is not reachable", j-1, nodes[j][0], nodes[j][1])
+		}
+		if math.IsInf(d[j][n+1], 1) {
+			return nil, fmt.Errorf("end (%d, %d) is not reachable from stop %d", end[0], end[1], j-1)
+		}
+	}
+	if math.IsInf(d[0][n+1], 1) {
+		return nil, fmt.Errorf("end (%d, %d) is not reachable", end[0], end[1])
+	}
+
+	var order []int
+	if exactLimit > 16 {
+		exactLimit = 16
+	}
+	if n <= exactLimit {
+		order = heldKarp(d, n)
+	} else {
+		order = improveTour(d, nearestNeighbor(d, n))
+	}
+	t := &Tour{Order: order}
+	if order != nil {
+		t.Cost = tourCost(d, order)
+	}
+	if order == nil || math.IsInf(t.Cost, 1) {
+		return nil, fmt.Errorf("no tour from (%d, %d) to (%d, %d) through %d stops", start[0], start[1], end[0], end[1], n)
+	}
+	prev := 0
+	for k := 0; k <= n; k++ {
+		next := n + 1
+		if k < n {
+			next = order[k] + 1
+			t.Stops = append(t.Stops, stops[order[k]])
+		}
+		s, g := nodes[prev], nodes[next]
+		rt, _ := fields[next].Route(s[0], s[1])
+		t.Legs = append(t.Legs, Leg{From: s, To: g, Route: rt, Cost: d[prev][next]})
+		prev = next
+	}
+	t.Route = ConcatRoutes(t.Legs)
+	return t, a.checkUnknown(t.Route)
+}
@@ -0,0 +1,64 @@
+package astar_wr
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOptimizeTourLegs(t *testing.T) {
+	var objects [][2]int
+	for y := 0; y < 20; y++ {
+		objects = append(objects, [2]int{10, y})
+	}
+	a := NewWeightedAstar(objects, 30, 30, 2)
+	stops := [][2]int{{25, 5}, {5, 25}, {20, 20}, {3, 8}, {28, 28}}
+	for _, limit := range []int{16, 0} { // Held-Karp and heuristic
+		tour, err := a.OptimizeTour([2]int{1, 1}, [2]int{1, 1}, stops, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0.0
+		for i, l := range tour.Legs {
+			c, blocked := a.RouteCost(l.Route)
+			if blocked >= 0 || math.Abs(c-l.Cost) > 1e-9 {
+				t.Errorf("limit %d leg %d: route cost %f (blocked %d), leg cost %f", limit, i, c, blocked, l.Cost)
+			}
+			if l.Route[0] != l.To || l.Route[len(l.Route)-1] != l.From {
+				t.Errorf("limit %d leg %d: route from %v to %v", limit, i, l.Route[len(l.Route)-1], l.Route[0])
+			}
+			sum += l.Cost
+		}
+		if math.Abs(sum-tour.Cost) > 1e-9 || len(tour.Stops) != len(stops) {
+			t.Errorf("limit %d: tour cost %f, sum of legs %f, %d stops", limit, tour.Cost, sum, len(tour.Stops))
+		}
+	}
+}
+
+func TestOptimizeTourUnreachable(t *testing.T) {
+	// (25,25) is walled off
+	var objects [][2]int
+	for i := 23; i < 28; i++ {
+		objects = append(objects, [2]int{i, 23}, [2]int{i, 27}, [2]int{23, i}, [2]int{27, i})
+	}
+	a := NewWeightedAstar(objects, 30, 30, 0)
+	stops := [][2]int{{5, 5}, {25, 25}, {10, 20}}
+	for _, limit := range []int{16, 0} {
+		if _, err := a.OptimizeTour([2]int{1, 1}, [2]int{1, 1}, stops, limit); err == nil || !strings.Contains(err.Error(), "stop 1 ") {
+			t.Errorf("limit %d: tour through walled off stop: %v", limit, err)
+		}
+	}
+	if _, err := a.OptimizeTour([2]int{1, 1}, [2]int{25, 25}, stops[:1], 16); err == nil {
+		t.Error("tour to walled off end is found")
+	}
+	// every stop is reachable, but no order visits all (one-way)
+	d := [][]float64{
+		{0, 1, 1, 1},
+		{math.Inf(1), 0, math.Inf(1), 1},
+		{math.Inf(1), math.Inf(1), 0, 1},
+		{0, 0, 0, 0},
+	}
+	if order := heldKarp(d, 2); order != nil {
+		t.Errorf("heldKarp returns %v without tour", order)
+	}
+}
@@ -0,0 +1,128 @@
+package astar_wr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Unknown space handling for SLAM maps (free / occupied / unknown)
+
+// CellState is the three-state cell model
+type CellState byte
+
+const (
+	CellFree CellState = iota
+	CellOccupied
+	CellUnknown
+)
+
+// UnknownPolicy is how unknown cells are planned
+type UnknownPolicy int
+
+const (
+	UnknownLethal   UnknownPolicy = iota // never pass unknown cells
+	UnknownHighCost                      // pass with cost
+	UnknownFree                          // treat as free (exploration)
+)
+
+// UnknownSpaceError is returned by Plan with the route when the route passes unknown cells.
+type UnknownSpaceError struct {
+	Cells [][2]int
+}
+
+func (e *UnknownSpaceError) Error() string {
+	return fmt.Sprintf("route passes %d unknown cells (first (%d, %d))", len(e.Cells), e.Cells[0][0], e.Cells[0][1])
+}
+
+// CellStateMap reads gray-scale image into three-state cells.
+// gray < closeThreth is occupied, gray == unknown (205 in ROS map) is unknown.
+func CellStateMap(imData image.Image, closeThreth, unknown int) [][]CellState {
+	bound := imData.Bounds()
+	W := bound.Dx()
+	H := bound.Dy()
+	states := make([][]CellState, W)
+	for x := 0; x < W; x++ {
+		states[x] = make([]CellState, H)
+		for y := 0; y < H; y++ {
+			pixel := int(color.GrayModel.Convert(imData.At(bound.Min.X+x, bound.Min.Y+y)).(color.Gray).Y)
+			if pixel < closeThreth {
+				states[x][y] = CellOccupied
+			} else if pixel == unknown {
+				states[x][y] = CellUnknown
+			}
+		}
+	}
+	return states
+}
+
+// LayerUnknown is the layer name of ApplyUnknown, it is just above the static layer
+// so unknown cells made lethal are inflated as other obstacles.
+const LayerUnknown = "unknown"
+
+// ApplyUnknown marks unknown cells of states and sets their cost by policy
+// (UnknownLethal: 0xff, UnknownHighCost: cost, UnknownFree: 0) on the unknown layer.
+// the cost map is re-inflated and listeners are notified.
+func (a *Astar) ApplyUnknown(states [][]CellState, policy UnknownPolicy, cost byte) {
+	if a.Unknown == nil {
+		a.Unknown = make([][]bool, a.Width)
+		for x := range a.Unknown {
+			a.Unknown[x] = make([]bool, a.Height)
+		}
+	}
+	m := a.layers()
+	l := m.Layer(LayerUnknown)
+	if l == nil {
+		l = m.insertLayer(LayerUnknown, CombineOverride, LayerStatic)
+	}
+	var c byte
+	switch policy {
+	case UnknownLethal:
+		c = 0xff
+	case UnknownHighCost:
+		c = cost
+	}
+	for x := 0; x < a.Width && x < len(states); x++ {
+		for y := 0; y < a.Height && y < len(states[x]); y++ {
+			if states[x][y] != CellUnknown {
+				continue
+			}
+			a.Unknown[x][y] = true
+			l.Set(x, y, c)
+		}
+	}
+	m.Update()
+}
+
+// State returns three-state of the cell (out of map is occupied)
+func (a *Astar) State(x, y int) CellState {
+	if x < 0 || y < 0 || x >= a.Width || y >= a.Height || a.CostMap[x][y] == 0xff {
+		return CellOccupied
+	}
+	if a.Unknown != nil && a.Unknown[x][y] {
+		return CellUnknown
+	}
+	return CellFree
+}
+
+// UnknownCells returns unknown cells on the route
+func (a *Astar) UnknownCells(route [][2]int) [][2]int {
+	cells := make([][2]int, 0)
+	if a.Unknown == nil {
+		return cells
+	}
+	for _, p := range route {
+		if a.State(p[0], p[1]) == CellUnknown {
+			cells = append(cells, p)
+		}
+	}
+	return cells
+}
+
+// checkUnknown returns UnknownSpaceError if the route passes unknown cells
+func (a *Astar) checkUnknown(route [][2]int) error {
+	if cells := a.UnknownCells(route); len(cells) > 0 {
+		return &UnknownSpaceError{Cells: cells}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package astar_wr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// unknownImage is a free 20x10 map with unknown block [8,11]x[0,9] (ROS colors)
+func unknownImage() *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, 20, 10))
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 10; y++ {
+			g := uint8(254)
+			if x >= 8 && x <= 11 {
+				g = 205
+			}
+			im.SetGray(x, y, color.Gray{g})
+		}
+	}
+	return im
+}
+
+func TestApplyUnknownPolicy(t *testing.T) {
+	opt := DefaultGrayCostOption()
+	opt.Iteration = 2
+	cases := []struct {
+		policy UnknownPolicy
+		cost   byte // cost of the unknown cell (10,5)
+		near   byte // cost of the free cell (7,5) next to unknown block
+	}{
+		{UnknownLethal, 0xff, inflationCost(1, 2)},
+		{UnknownHighCost, 100, 0},
+		{UnknownFree, 0, 0},
+	}
+	for _, c := range cases {
+		im := unknownImage()
+		a := GrayCostAstar(im, opt)
+		if a.CostMap[10][5] != opt.UnknownCost {
+			t.Fatalf("unknown cost %d before ApplyUnknown", a.CostMap[10][5])
+		}
+		version := a.Version
+		a.ApplyUnknown(CellStateMap(im, opt.LethalThreth, opt.Unknown), c.policy, 100)
+		if a.CostMap[10][5] != c.cost || a.CostMap[7][5] != c.near {
+			t.Errorf("policy %d: cost %d near %d, want %d near %d", c.policy, a.CostMap[10][5], a.CostMap[7][5], c.cost, c.near)
+		}
+		if a.Version == version {
+			t.Errorf("policy %d: version is not incremented", c.policy)
+		}
+		if a.State(10, 5) != CellUnknown && c.policy != UnknownLethal {
+			t.Errorf("policy %d: state %d, want unknown", c.policy, a.State(10, 5))
+		}
+		_, err := a.Plan(2, 5, 17, 5, 1)
+		if c.policy == UnknownLethal {
+			if err == nil {
+				t.Errorf("policy %d: Plan through unknown should fail", c.policy)
+			}
+		} else if _, ok := err.(*UnknownSpaceError); !ok {
+			t.Errorf("policy %d: Plan returns %v, want UnknownSpaceError", c.policy, err)
+		}
+	}
+}
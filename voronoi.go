@@ -0,0 +1,304 @@
+package astar_wr
+
+import "math"
+
+// Generalized Voronoi diagram (medial axis) of free space
+// every cell gets its nearest obstacle cell by brushfire (feature transform),
+// cells whose neighbor has a distant nearest obstacle cell are the skeleton (middle of aisles).
+// walls connected into one object (shelves on the outer wall) also have the skeleton between them.
+// aisles of one cell width are the skeleton themselves and connected to it at their ends.
+
+// LayerLanes is the layer name of ApplyLanes
+const LayerLanes = "lanes"
+
+// skeletonGap is min distance [cell] between nearest obstacle cells of neighbors on the skeleton,
+// smaller gaps are the same side of an obstacle (e.g. around a convex corner).
+const skeletonGap = 3
+
+// Voronoi is the skeleton of free space and its graph
+type Voronoi struct {
+	Width, Height int
+	Cells         [][2]int  // skeleton cells
+	Clearance     []float64 // distance to the nearest obstacle, index is y*Width+x
+	Roadmap       *Roadmap  // nodes are junctions and ends of the skeleton
+
+	skeleton []bool
+}
+
+// VoronoiRoadmap computes the skeleton of free space around objects (from ObjectMap).
+// if objects is nil, lethal cells of CostMap are used. only traversable cells are on the skeleton.
+func VoronoiRoadmap(a *Astar, objects [][2]int) *Voronoi {
+	if objects == nil {
+		for x := 0; x < a.Width; x++ {
+			for y := 0; y < a.Height; y++ {
+				if a.CostMap[x][y] == 0xff {
+					objects = append(objects, [2]int{x, y})
+				}
+			}
+		}
+	}
+	w, h := a.Width, a.Height
+	dist := make([]float64, w*h)
+	nearest := make([]int32, w*h) // nearest obstacle cell
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		nearest[i] = -1
+	}
+	for _, o := range objects {
+		if o[0] >= 0 && o[1] >= 0 && o[0] < w && o[1] < h {
+			i := o[1]*w + o[0]
+			dist[i], nearest[i] = 0, int32(i)
+		}
+	}
+	for i := range dist {
+		x, y := i%w, i/w
+		if dist[i] != 0 && (x == 0 || y == 0 || x == a.MaxX || y == a.MaxY) {
+			dist[i], nearest[i] = 1, int32(i) // map border is an obstacle
+		}
+	}
+	a.chamfer(dist, nearest)
+	gap := func(i, j int32) float64 {
+		return math.Hypot(float64(i%int32(w)-j%int32(w)), float64(i/int32(w)-j/int32(w)))
+	}
+
+	blocked := func(x, y int) bool {
+		return x < 0 || x > a.MaxX || y < 0 || y > a.MaxY || dist[y*w+x] == 0
+	}
+
+	v := &Voronoi{Width: w, Height: h, Clearance: dist, skeleton: make([]bool, w*h)}
+	var narrow []int // cells of one cell wide aisles
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if dist[i] == 0 || !a.verifyPoint(x, y) {
+				continue
+			}
+			// aisle of one cell width is its own middle (no neighbor is across it)
+			if blocked(x-1, y) && blocked(x+1, y) || blocked(x, y-1) && blocked(x, y+1) {
+				v.skeleton[i] = true
+				v.Cells = append(v.Cells, [2]int{x, y})
+				narrow = append(narrow, i)
+				continue
+			}
+			for k := 0; k < 4; k++ { // axis neighbors
+				nx, ny := x+int(motion[k][0]), y+int(motion[k][1])
+				if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY {
+					continue
+				}
+				n := ny*w + nx
+				// the farther side of the boundary is the skeleton
+				if dist[n] > 0 && gap(nearest[i], nearest[n]) >= skeletonGap && (dist[i] > dist[n] || dist[i] == dist[n] && i < n) {
+					v.skeleton[i] = true
+					v.Cells = append(v.Cells, [2]int{x, y})
+					break
+				}
+			}
+		}
+	}
+	// ends of narrow aisles are connected to the skeleton by climbing the clearance
+	for _, i := range narrow {
+		for _, m := range motion[:4] {
+			x, y := i%w+int(m[0]), i/w+int(m[1])
+			if !blocked(x, y) && a.verifyPoint(x, y) && !v.skeleton[y*w+x] {
+				v.climb(a, x, y, i)
+			}
+		}
+	}
+	v.Roadmap = v.graph(a)
+	return v
+}
+
+// climb adds cells to the skeleton from (x,y) to higher clearance until it touches the skeleton
+// (other than the cell from)
+func (v *Voronoi) climb(a *Astar, x, y, from int) {
+	w := v.Width
+	var cells []int
+	for {
+		i := y*w + x
+		cells = append(cells, i)
+		if v.touches(a, x, y, from) {
+			break
+		}
+		best, bi := v.Clearance[i], -1
+		for _, m := range motion {
+			nx, ny := x+int(m[0]), y+int(m[1])
+			if nx < 0 || nx > a.MaxX || ny < 0 || ny > a.MaxY || !a.verifyPoint(nx, ny) {
+				continue
+			}
+			if n := ny*w + nx; v.Clearance[n] > best {
+				best, bi = v.Clearance[n], n
+			}
+		}
+		if bi < 0 { // local max without the skeleton
+			return
+		}
+		x, y = bi%w, bi/w
+	}
+	for _, i := range cells {
+		v.skeleton[i] = true
+		v.Cells = append(v.Cells, [2]int{i % w, i / w})
+	}
+}
+
+// touches returns true if (x,y) or its neighbor except the cell from is on the skeleton
+func (v *Voronoi) touches(a *Astar, x, y, from int) bool {
+	if v.skeleton[y*v.Width+x] {
+		return true
+	}
+	for _, m := range motion {
+		nx, ny := x+int(m[0]), y+int(m[1])
+		if n := ny*v.Width + nx; n != from && v.IsLane(nx, ny) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLane returns true if (x,y) is on the skeleton
+func (v *Voronoi) IsLane(x, y int) bool {
+	if x < 0 || y < 0 || x >= v.Width || y >= v.Height {
+		return false
+	}
+	return v.skeleton[y*v.Width+x]
+}
+
+// graph traces skeleton cells between junctions / ends into roadmap edges
+func (v *Voronoi) graph(a *Astar) *Roadmap {
+	w := v.Width
+	r := &Roadmap{Width: v.Width, Height: v.Height, Version: a.Version}
+	neighbors := func(i int) []int {
+		var ns []int
+		for _, m := range motion {
+			x, y := i%w+int(m[0]), i/w+int(m[1])
+			if v.IsLane(x, y) {
+				ns = append(ns, y*w+x)
+			}
+		}
+		return ns
+	}
+	node := make(map[int]int) // cell -> node
+	addNode := func(i int) int {
+		if n, ok := node[i]; ok {
+			return n
+		}
+		node[i] = r.AddNode(RoadNode{X: i % w, Y: i / w, Clearance: v.Clearance[i]})
+		return node[i]
+	}
+	for _, c := range v.Cells {
+		if i := c[1]*w + c[0]; len(neighbors(i)) != 2 {
+			addNode(i)
+		}
+	}
+	visited := make(map[int]bool) // cells inside traced edges
+	trace := func(u, q int) {
+		path := []int{u, q}
+		prev, cur := u, q
+		for _, ok := node[cur]; !ok; _, ok = node[cur] {
+			visited[cur] = true
+			next := -1
+			for _, n := range neighbors(cur) {
+				if _, isNode := node[n]; n != prev && (isNode || !visited[n]) {
+					next = n
+					break
+				}
+			}
+			if next < 0 { // closed loop, end here
+				addNode(cur)
+				break
+			}
+			prev, cur = cur, next
+			path = append(path, cur)
+		}
+		v.addEdges(a, r, node[u], node[cur], path)
+	}
+	for {
+		for _, c := range v.Cells {
+			u := c[1]*w + c[0]
+			if _, ok := node[u]; !ok {
+				continue
+			}
+			for _, q := range neighbors(u) {
+				if _, ok := node[q]; ok {
+					if u < q {
+						v.addEdges(a, r, node[u], node[q], []int{u, q})
+					}
+				} else if !visited[q] {
+					trace(u, q)
+				}
+			}
+		}
+		// loops without junction get a node
+		loop := -1
+		for _, c := range v.Cells {
+			i := c[1]*w + c[0]
+			if _, ok := node[i]; !ok && !visited[i] {
+				loop = i
+				break
+			}
+		}
+		if loop < 0 {
+			break
+		}
+		addNode(loop)
+	}
+	return r
+}
+
+// addEdges adds edges u->v and v->u along path (cell indexes) if the moves are allowed
+func (v *Voronoi) addEdges(a *Astar, r *Roadmap, u, t int, path []int) {
+	w := v.Width
+	cells := make([][2]int, len(path))
+	for i, p := range path {
+		cells[i] = [2]int{p % w, p / w}
+	}
+	clr := pathClearance(v.Clearance, w, cells)
+	cost := func(cells [][2]int) (float64, bool) {
+		c := 0.0
+		for i := 1; i < len(cells); i++ {
+			mc, ok := a.moveCost(cells[i-1][0], cells[i-1][1], motionOf(cells[i][0]-cells[i-1][0], cells[i][1]-cells[i-1][1]))
+			if !ok {
+				return 0, false
+			}
+			c += mc
+		}
+		return c, true
+	}
+	if c, ok := cost(cells); ok {
+		r.AddEdge(RoadEdge{From: u, To: t, Cost: c, Clearance: clr, Path: cells})
+	}
+	rev := make([][2]int, len(cells))
+	for i, p := range cells {
+		rev[len(cells)-1-i] = p
+	}
+	if c, ok := cost(rev); ok {
+		r.AddEdge(RoadEdge{From: t, To: u, Cost: c, Clearance: clr, Path: rev})
+	}
+}
+
+// ApplyLanes adds "lanes" layer which makes cells off the skeleton expensive,
+// cost increases with distance to the skeleton up to penalty at width.
+// Plan prefers the middle of aisles after the layer is applied.
+func (v *Voronoi) ApplyLanes(m *LayeredCostMap, width float64, penalty byte) {
+	a := m.Astar
+	l := m.Layer(LayerLanes)
+	if l == nil {
+		l = m.AddLayer(LayerLanes, CombineSum)
+	}
+	dist := make([]float64, v.Width*v.Height)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		if v.skeleton[i] {
+			dist[i] = 0
+		}
+	}
+	a.chamfer(dist, nil)
+	for i, d := range dist {
+		x, y := i%v.Width, i/v.Width
+		if d == 0 {
+			l.Clear(x, y)
+			continue
+		}
+		l.Set(x, y, byte(math.Round(float64(penalty)*math.Min(1, d/width))))
+	}
+	m.Update()
+}
@@ -0,0 +1,124 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+// frameMap is 40x24 room with closed outer wall and shelves attached to the top wall
+func frameMap() *Astar {
+	var objects [][2]int
+	w, h := 40, 24
+	for x := 0; x < w; x++ {
+		objects = append(objects, [2]int{x, 0}, [2]int{x, h - 1})
+	}
+	for y := 0; y < h; y++ {
+		objects = append(objects, [2]int{0, y}, [2]int{w - 1, y})
+	}
+	for x := 8; x < 36; x += 9 {
+		for y := 1; y < 16; y++ {
+			objects = append(objects, [2]int{x, y}, [2]int{x + 1, y})
+		}
+	}
+	return NewWeightedAstar(objects, w, h, 1)
+}
+
+func TestVoronoiClosedWall(t *testing.T) {
+	a := frameMap()
+	v := VoronoiRoadmap(a, nil)
+	if len(v.Cells) == 0 || len(v.Roadmap.Nodes) == 0 {
+		t.Fatalf("%d skeleton cells, %d nodes", len(v.Cells), len(v.Roadmap.Nodes))
+	}
+	if n := roadmapComponents(v.Roadmap); n != 1 {
+		t.Errorf("roadmap has %d components", n)
+	}
+	if r, err := ExtractRoadmap(a, 6); err != nil || roadmapComponents(r) != 1 {
+		t.Errorf("ExtractRoadmap: %v", err)
+	}
+	// middle of the aisles between shelves (x=10..16, 19..25, 28..34) and of the bottom aisle
+	for _, c := range [][2]int{{13, 8}, {22, 8}, {31, 8}, {20, 19}} {
+		if !v.IsLane(c[0], c[1]) {
+			t.Errorf("(%d, %d) is not on the skeleton", c[0], c[1])
+		}
+	}
+	for _, c := range [][2]int{{11, 8}, {15, 8}, {20, 21}} {
+		if v.IsLane(c[0], c[1]) {
+			t.Errorf("(%d, %d) is on the skeleton", c[0], c[1])
+		}
+	}
+	for _, e := range v.Roadmap.Edges {
+		if e.Clearance < 1 {
+			t.Errorf("edge %d->%d has clearance %f", e.From, e.To, e.Clearance)
+		}
+	}
+
+	// lanes make Plan drive the middle of the aisle
+	v.ApplyLanes(NewLayeredCostMap(a, nil, 1), 3, 30)
+	route, err := a.Plan(11, 2, 11, 14, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lane := 0
+	for _, p := range route {
+		if v.IsLane(p[0], p[1]) {
+			lane++
+		}
+	}
+	if lane < len(route)/2 {
+		t.Errorf("%d of %d cells are on the lane", lane, len(route))
+	}
+}
+
+// narrowMap is 40x24 room split by a wall with a door of one cell (x=20),
+// shelves on the top wall have an aisle of one cell between them (x=11)
+func narrowMap() *Astar {
+	var objects [][2]int
+	w, h := 40, 24
+	for x := 0; x < w; x++ {
+		objects = append(objects, [2]int{x, 0}, [2]int{x, h - 1})
+	}
+	for y := 0; y < h; y++ {
+		objects = append(objects, [2]int{0, y}, [2]int{w - 1, y})
+	}
+	for x := 1; x < w-1; x++ {
+		for y := 10; y < 14; y++ {
+			if x != 20 {
+				objects = append(objects, [2]int{x, y})
+			}
+		}
+	}
+	for y := 1; y < 7; y++ {
+		objects = append(objects, [2]int{10, y}, [2]int{12, y})
+	}
+	return NewWeightedAstar(objects, w, h, 0)
+}
+
+func TestVoronoiNarrowAisle(t *testing.T) {
+	a := narrowMap()
+	v := VoronoiRoadmap(a, nil)
+	for y := 1; y < 14; y++ {
+		if x := 20; y >= 10 && !v.IsLane(x, y) {
+			t.Errorf("door (%d, %d) is not on the skeleton", x, y)
+		}
+		if x := 11; y < 7 && !v.IsLane(x, y) {
+			t.Errorf("aisle (%d, %d) is not on the skeleton", x, y)
+		}
+	}
+	if n := roadmapComponents(v.Roadmap); n != 1 {
+		t.Errorf("roadmap has %d components", n)
+	}
+	r, err := ExtractRoadmap(a, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := BuildContractionHierarchy(r)
+	for _, c := range [][4]int{{5, 5, 30, 20}, {11, 1, 30, 20}} {
+		route, cost, err := ch.Route(a, c[0], c[1], c[2], c[3])
+		if err != nil {
+			t.Fatalf("Route%v: %v", c, err)
+		}
+		if rc, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(rc-cost) > 1e-9 {
+			t.Errorf("Route%v: route cost %f (blocked %d), cost %f", c, rc, blocked, cost)
+		}
+	}
+}
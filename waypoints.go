@@ -0,0 +1,46 @@
+package astar_wr
+
+import "fmt"
+
+// Routing through ordered waypoints (picking tours)
+
+// Leg is a route between two successive waypoints (Plan format)
+type Leg struct {
+	From, To [2]int
+	Route    [][2]int
+	Cost     float64
+}
+
+// PlanWaypoints plans route through waypoints in order (first is start, last is goal).
+// legs are concatenated without duplicate junction points, route is in Plan format (goal first).
+// if the route passes unknown cells, the route is returned with *UnknownSpaceError
+func (a *Astar) PlanWaypoints(waypoints [][2]int, weight float64) (route [][2]int, legs []Leg, cost float64, err error) {
+	if len(waypoints) < 2 {
+		return nil, nil, 0, fmt.Errorf("need at least 2 waypoints (%d)", len(waypoints))
+	}
+	legs = make([]Leg, len(waypoints)-1)
+	for i := range legs {
+		s, g := waypoints[i], waypoints[i+1]
+		rt, e := a.Plan(s[0], s[1], g[0], g[1], weight)
+		if _, unknown := e.(*UnknownSpaceError); e != nil && !unknown {
+			return nil, legs[:i], cost, fmt.Errorf("leg %d (%d,%d)->(%d,%d): %v", i, s[0], s[1], g[0], g[1], e)
+		}
+		c, _ := a.RouteCost(rt)
+		legs[i] = Leg{From: s, To: g, Route: rt, Cost: c}
+		cost += c
+	}
+	route = ConcatRoutes(legs)
+	return route, legs, cost, a.checkUnknown(route)
+}
+
+// ConcatRoutes joins routes of legs into one route (Plan format)
+func ConcatRoutes(legs []Leg) (route [][2]int) {
+	for i := len(legs) - 1; i >= 0; i-- {
+		rt := legs[i].Route
+		if len(route) > 0 && len(rt) > 0 && route[len(route)-1] == rt[0] {
+			rt = rt[1:] // junction point
+		}
+		route = append(route, rt...)
+	}
+	return route
+}
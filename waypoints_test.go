@@ -0,0 +1,76 @@
+package astar_wr
+
+import (
+	"math"
+	"testing"
+)
+
+// shelfMap is 60x40 map with shelves (lines of objects) and aisles
+func shelfMap() *Astar {
+	var objects [][2]int
+	for x := 8; x < 52; x += 8 {
+		for y := 5; y < 35; y++ {
+			objects = append(objects, [2]int{x, y}, [2]int{x + 1, y})
+		}
+	}
+	return NewWeightedAstar(objects, 60, 40, 2)
+}
+
+func TestPlanWaypoints(t *testing.T) {
+	a := shelfMap()
+	wps := [][2]int{{2, 2}, {12, 30}, {28, 10}, {57, 37}}
+	route, legs, cost, err := a.PlanWaypoints(wps, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 3 {
+		t.Fatalf("%d legs", len(legs))
+	}
+	sum := 0.0
+	for i, l := range legs {
+		if l.From != wps[i] || l.To != wps[i+1] || l.Route[0] != l.To || l.Route[len(l.Route)-1] != l.From {
+			t.Errorf("leg %d from %v to %v, route from %v to %v", i, l.From, l.To, l.Route[len(l.Route)-1], l.Route[0])
+		}
+		if c, blocked := a.RouteCost(l.Route); blocked >= 0 || math.Abs(l.Cost-c) > 1e-9 {
+			t.Errorf("leg %d cost %f, route cost %f (blocked %d)", i, l.Cost, c, blocked)
+		}
+		f, _ := a.Dijkstra(l.From[0], l.From[1])
+		if opt := f.Cost(l.To[0], l.To[1]); l.Cost < opt-1e-9 {
+			t.Errorf("leg %d cost %f, optimal %f", i, l.Cost, opt)
+		}
+		sum += l.Cost
+	}
+	if math.Abs(cost-sum) > 1e-9 {
+		t.Errorf("cost %f, sum of legs %f", cost, sum)
+	}
+	if c, blocked := a.RouteCost(route); blocked >= 0 || math.Abs(c-cost) > 1e-9 {
+		t.Errorf("route cost %f (blocked %d), want %f", c, blocked, cost)
+	}
+	if route[0] != wps[3] || route[len(route)-1] != wps[0] {
+		t.Errorf("route from %v to %v", route[len(route)-1], route[0])
+	}
+	// junction points are not repeated
+	want := -len(legs) + 1
+	for _, l := range legs {
+		want += len(l.Route)
+	}
+	if len(route) != want {
+		t.Errorf("route has %d cells, want %d", len(route), want)
+	}
+	for i := 1; i < len(route); i++ {
+		if route[i] == route[i-1] {
+			t.Errorf("cell %v is repeated", route[i])
+		}
+	}
+}
+
+func TestPlanWaypointsFail(t *testing.T) {
+	a := shelfMap()
+	if _, _, _, err := a.PlanWaypoints([][2]int{{2, 2}}, 1); err == nil {
+		t.Error("one waypoint is accepted")
+	}
+	_, legs, _, err := a.PlanWaypoints([][2]int{{2, 2}, {12, 30}, {8, 10}, {57, 37}}, 1) // (8,10) is a shelf
+	if err == nil || len(legs) != 1 {
+		t.Errorf("waypoint on a shelf: %v, %d legs", err, len(legs))
+	}
+}
@@ -25,28 +25,35 @@ func (a *Astar) checkOuter(x, y int, cost byte) byte {
 	return cost
 }
 
-// WeightedMap read glay-scale image and generate Image
-func WeightedAstar(objects [][2]int, iteration int) *Astar {
+// newAstar allocates empty cost map with width x height
+func newAstar(width, height int) *Astar {
 	a := &Astar{
-		MaxX: 0,
-		MaxY: 0,
+		MaxX:   width - 1,
+		MaxY:   height - 1,
+		Width:  width,
+		Height: height,
+	}
+	a.CostMap = make([][]byte, a.Width)
+	for i := 0; i < a.Width; i++ {
+		a.CostMap[i] = make([]byte, a.Height)
 	}
+	a.MaxIndex = (a.Width)*(a.Height) - 1
+	return a
+}
 
+// WeightedMap read glay-scale image and generate Image
+// map size is taken from the max object coordinate, use NewWeightedAstar to keep image size.
+func WeightedAstar(objects [][2]int, iteration int) *Astar {
+	maxX, maxY := 0, 0
 	for _, obj := range objects {
-		if obj[0] > a.MaxX {
-			a.MaxX = obj[0]
+		if obj[0] > maxX {
+			maxX = obj[0]
 		}
-		if obj[1] > a.MaxY {
-			a.MaxY = obj[1]
+		if obj[1] > maxY {
+			maxY = obj[1]
 		}
 	}
-	a.Width = a.MaxX + 1
-	a.Height = a.MaxY + 1
-
-	a.CostMap = make([][]byte, a.Width)
-	for i := 0; i < a.Width; i++ {
-		a.CostMap[i] = make([]byte, a.Height)
-	}
+	a := newAstar(maxX+1, maxY+1)
 
 	bmap := make([][]byte, a.Width)
 	for i := 0; i < a.Width; i++ {
@@ -74,6 +81,7 @@ func WeightedAstar(objects [][2]int, iteration int) *Astar {
 	}
 
 	a.MaxIndex = (a.Width)*(a.Height) - 1
+	a.Iteration = iteration
 	return a
 }
 
@@ -98,3 +106,18 @@ func ObjectMap(imData image.Image, closeThreth int) ([][2]int, error) {
 	}
 	return data, nil
 }
+
+// NewWeightedAstar generates Astar with explicit map size (width x height).
+// objects out of the map are ignored, cells out of the map are always blocked.
+func NewWeightedAstar(objects [][2]int, width, height, iteration int) *Astar {
+	o := NewOccupancyGrid(width, height)
+	for _, obj := range objects {
+		o.Set(obj[0], obj[1])
+	}
+	return OccupancyAstar(o, iteration)
+}
+
+// WeightedAstarBounds generates Astar with the size of image bounds (e.g. imData.Bounds()).
+func WeightedAstarBounds(objects [][2]int, bound image.Rectangle, iteration int) *Astar {
+	return NewWeightedAstar(objects, bound.Dx(), bound.Dy(), iteration)
+}
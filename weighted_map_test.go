@@ -0,0 +1,71 @@
+package astar_wr
+
+import "testing"
+
+func TestNewWeightedAstarSize(t *testing.T) {
+	objects := [][2]int{{2, 2}, {30, 30}} // (30,30) is out of the map
+	a := NewWeightedAstar(objects, 20, 10, 2)
+	if a.Width != 20 || a.Height != 10 || a.MaxX != 19 || a.MaxY != 9 {
+		t.Fatalf("size %dx%d (max %d,%d), want 20x10", a.Width, a.Height, a.MaxX, a.MaxY)
+	}
+	if a.CostMap[2][2] != 0xff || a.CostMap[3][2] != inflationCost(1, 2) {
+		t.Errorf("object cost %d, inflation cost %d", a.CostMap[2][2], a.CostMap[3][2])
+	}
+}
+
+func TestPlanEdgeOfMap(t *testing.T) {
+	// objects only in the left top corner, WeightedAstar would make 3x3 map
+	a := NewWeightedAstar([][2]int{{2, 2}}, 20, 10, 1)
+
+	cases := []struct {
+		sx, sy, gx, gy int
+	}{
+		{0, 0, 19, 9},  // corner to corner
+		{19, 0, 0, 9},  // right top to left bottom
+		{19, 5, 10, 9}, // right edge to bottom edge
+		{19, 9, 19, 0}, // along right edge
+	}
+	for _, c := range cases {
+		route, err := a.Plan(c.sx, c.sy, c.gx, c.gy, 0.5)
+		if err != nil {
+			t.Errorf("Plan(%d,%d,%d,%d): %v", c.sx, c.sy, c.gx, c.gy, err)
+			continue
+		}
+		if route[0] != [2]int{c.gx, c.gy} || route[len(route)-1] != [2]int{c.sx, c.sy} {
+			t.Errorf("Plan(%d,%d,%d,%d): route from %v to %v", c.sx, c.sy, c.gx, c.gy, route[len(route)-1], route[0])
+		}
+		for _, p := range route {
+			if p[0] < 0 || p[0] > a.MaxX || p[1] < 0 || p[1] > a.MaxY {
+				t.Errorf("route point %v is out of map", p)
+			}
+		}
+	}
+}
+
+func TestPlanOutOfMap(t *testing.T) {
+	a := NewWeightedAstar([][2]int{{2, 2}}, 20, 10, 1)
+
+	cases := []struct {
+		sx, sy, gx, gy int
+	}{
+		{20, 0, 0, 0}, // start right of the map
+		{0, 10, 0, 0}, // start below the map
+		{-1, 5, 0, 0}, // start left of the map
+		{0, 0, 20, 9}, // goal right of the map
+		{0, 0, 5, -1}, // goal above the map
+		{0, 0, 2, 2},  // goal is object
+	}
+	for _, c := range cases {
+		if _, err := a.Plan(c.sx, c.sy, c.gx, c.gy, 0.5); err == nil {
+			t.Errorf("Plan(%d,%d,%d,%d) should fail", c.sx, c.sy, c.gx, c.gy)
+		}
+	}
+}
+
+func TestWeightedAstarLastColumn(t *testing.T) {
+	// last column/row of the derived map is inside of the map
+	a := WeightedAstar([][2]int{{9, 0}, {0, 9}}, 1)
+	if _, err := a.Plan(9, 5, 5, 9, 0.5); err != nil {
+		t.Errorf("Plan on last column/row: %v", err)
+	}
+}
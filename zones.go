@@ -0,0 +1,266 @@
+package astar_wr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Routing zones (keep-out / slow zone / preferred lane) defined by polygons
+// coordinates are pixel (cell) coordinates of the map image.
+
+// zone kinds
+const (
+	ZoneKeepOut = "keepout" // no-go area
+	ZoneSlow    = "slow"    // Cost is added
+	ZoneLane    = "lane"    // Cost is subtracted (preferred lane)
+	ZoneOneWay  = "oneway"  // Cost is added to reverse travel (0: forbidden), see ApplyDirections
+)
+
+// Zone is a polygon (or a line with Width) with routing rule.
+type Zone struct {
+	Name    string       `json:"name"`
+	Kind    string       `json:"kind"`
+	Cost    int          `json:"cost"`
+	Width   float64      `json:"width,omitempty"`   // for line zone (Polygon is polyline)
+	Heading *float64     `json:"heading,omitempty"` // travel direction [degree] of oneway polygon
+	Polygon [][2]float64 `json:"polygon"`
+}
+
+// ZoneSet is the content of a zone file
+//
+//	{"off_lane_cost": 5, "zones": [{"name":"aisle1","kind":"lane","cost":5,"polygon":[[10,10],[20,10],[20,200],[10,200]]}]}
+//
+// OffLaneCost is added to every cell outside of lanes, so lanes are preferred even on free space.
+type ZoneSet struct {
+	OffLaneCost int    `json:"off_lane_cost"`
+	Zones       []Zone `json:"zones"`
+}
+
+type geoJSON struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			Name    string   `json:"name"`
+			Kind    string   `json:"kind"`
+			Cost    int      `json:"cost"`
+			Width   float64  `json:"width"`
+			Heading *float64 `json:"heading"`
+		} `json:"properties"`
+	} `json:"features"`
+	Properties struct {
+		OffLaneCost int `json:"off_lane_cost"`
+	} `json:"properties"`
+}
+
+// LoadZoneFile reads zone file (plain json or GeoJSON FeatureCollection)
+func LoadZoneFile(fname string) (*ZoneSet, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadZones(file)
+}
+
+// LoadZones reads zone definition (plain json or GeoJSON FeatureCollection)
+func LoadZones(r io.Reader) (*ZoneSet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var gj geoJSON
+	if err := json.Unmarshal(data, &gj); err != nil {
+		return nil, err
+	}
+	var zs *ZoneSet
+	if gj.Type == "FeatureCollection" {
+		zs, err = zonesFromGeoJSON(&gj)
+	} else {
+		zs = &ZoneSet{}
+		err = json.Unmarshal(data, zs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zs.Zones {
+		if err := z.verify(); err != nil {
+			return nil, err
+		}
+	}
+	return zs, nil
+}
+
+func zonesFromGeoJSON(gj *geoJSON) (*ZoneSet, error) {
+	zs := &ZoneSet{OffLaneCost: gj.Properties.OffLaneCost}
+	for i, f := range gj.Features {
+		z := Zone{
+			Name:    f.Properties.Name,
+			Kind:    f.Properties.Kind,
+			Cost:    f.Properties.Cost,
+			Width:   f.Properties.Width,
+			Heading: f.Properties.Heading,
+		}
+		if z.Name == "" {
+			z.Name = fmt.Sprintf("feature%d", i)
+		}
+		switch f.Geometry.Type {
+		case "Polygon": // only outer ring is used
+			var rings [][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+				return nil, fmt.Errorf("zone %s: %v", z.Name, err)
+			}
+			if len(rings) > 0 {
+				z.Polygon = rings[0]
+			}
+			zs.Zones = append(zs.Zones, z)
+		case "MultiPolygon":
+			var polys [][][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &polys); err != nil {
+				return nil, fmt.Errorf("zone %s: %v", z.Name, err)
+			}
+			for _, rings := range polys {
+				if len(rings) > 0 {
+					pz := z
+					pz.Polygon = rings[0]
+					zs.Zones = append(zs.Zones, pz)
+				}
+			}
+		case "LineString":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &z.Polygon); err != nil {
+				return nil, fmt.Errorf("zone %s: %v", z.Name, err)
+			}
+			if z.Width <= 0 {
+				return nil, fmt.Errorf("zone %s: LineString needs width", z.Name)
+			}
+			zs.Zones = append(zs.Zones, z)
+		default:
+			return nil, fmt.Errorf("zone %s: unsupported geometry %s", z.Name, f.Geometry.Type)
+		}
+	}
+	return zs, nil
+}
+
+func (z Zone) verify() error {
+	switch z.Kind {
+	case ZoneKeepOut, ZoneSlow, ZoneLane, ZoneOneWay:
+	default:
+		return fmt.Errorf("zone %s: unknown kind %q", z.Name, z.Kind)
+	}
+	if z.Width > 0 {
+		if len(z.Polygon) < 2 {
+			return fmt.Errorf("zone %s: line needs 2 points", z.Name)
+		}
+	} else if len(z.Polygon) < 3 {
+		return fmt.Errorf("zone %s: polygon needs 3 points", z.Name)
+	}
+	return nil
+}
+
+// Contains returns true if the cell center (x,y) is in the zone.
+func (z Zone) Contains(x, y float64) bool {
+	if z.Width > 0 {
+		for i := 1; i < len(z.Polygon); i++ {
+			if segmentDist(x, y, z.Polygon[i-1], z.Polygon[i]) <= z.Width/2 {
+				return true
+			}
+		}
+		return false
+	}
+	in := false
+	n := len(z.Polygon)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		pi, pj := z.Polygon[i], z.Polygon[j]
+		if (pi[1] > y) != (pj[1] > y) && x < (pj[0]-pi[0])*(y-pi[1])/(pj[1]-pi[1])+pi[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+func segmentDist(x, y float64, p0, p1 [2]float64) float64 {
+	dx, dy := p1[0]-p0[0], p1[1]-p0[1]
+	l2 := dx*dx + dy*dy
+	t := 0.0
+	if l2 > 0 {
+		t = math.Max(0, math.Min(1, ((x-p0[0])*dx+(y-p0[1])*dy)/l2))
+	}
+	return math.Hypot(x-p0[0]-t*dx, y-p0[1]-t*dy)
+}
+
+// bounds returns cell bounding box of the zone
+func (z Zone) bounds() (int, int, int, int) {
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, p := range z.Polygon {
+		x0, x1 = math.Min(x0, p[0]), math.Max(x1, p[0])
+		y0, y1 = math.Min(y0, p[1]), math.Max(y1, p[1])
+	}
+	m := z.Width / 2
+	return int(math.Floor(x0 - m)), int(math.Floor(y0 - m)), int(math.Ceil(x1 + m)), int(math.Ceil(y1 + m))
+}
+
+// Cells calls fn for every map cell in the zone
+func (z Zone) Cells(a *Astar, fn func(x, y int)) {
+	bx0, by0, bx1, by1 := z.bounds()
+	x0, y0, x1, y1 := clipRect(a, bx0, by0, bx1, by1)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			if z.Contains(float64(x), float64(y)) {
+				fn(x, y)
+			}
+		}
+	}
+}
+
+// Apply rasterizes zones onto zones / keepout layers and updates the cost map.
+// previous contents of both layers are cleared.
+func (zs *ZoneSet) Apply(m *LayeredCostMap) error {
+	zl := m.Layer(LayerZones)
+	kl := m.Layer(LayerKeepOut)
+	if zl == nil || kl == nil {
+		return fmt.Errorf("layered cost map needs %s and %s layer", LayerZones, LayerKeepOut)
+	}
+	zl.ClearAll()
+	kl.ClearAll()
+	a := m.Astar
+	var lane [][]bool
+	if zs.OffLaneCost != 0 {
+		lane = make([][]bool, a.Width)
+		for x := range lane {
+			lane[x] = make([]bool, a.Height)
+		}
+	}
+	for _, z := range zs.Zones {
+		switch z.Kind {
+		case ZoneKeepOut:
+			z.Cells(a, func(x, y int) { kl.Set(x, y, 0xff) })
+		case ZoneSlow:
+			z.Cells(a, func(x, y int) { zl.Add(x, y, z.Cost) })
+		case ZoneLane:
+			z.Cells(a, func(x, y int) {
+				zl.Add(x, y, -z.Cost)
+				if lane != nil {
+					lane[x][y] = true
+				}
+			})
+		}
+	}
+	if lane != nil {
+		for x := range lane {
+			for y := range lane[x] {
+				if !lane[x][y] {
+					zl.Add(x, y, zs.OffLaneCost)
+				}
+			}
+		}
+	}
+	m.Update()
+	return nil
+}
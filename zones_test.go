@@ -0,0 +1,94 @@
+package astar_wr
+
+import (
+	"strings"
+	"testing"
+)
+
+const zonesGeoJSON = `{
+  "type": "FeatureCollection",
+  "properties": {"off_lane_cost": 7},
+  "features": [
+    {"type": "Feature", "properties": {"name": "wall", "kind": "keepout"},
+     "geometry": {"type": "Polygon", "coordinates": [[[2,2],[6,2],[6,6],[2,6],[2,2]]]}},
+    {"type": "Feature", "properties": {"name": "slow", "kind": "slow", "cost": 40},
+     "geometry": {"type": "MultiPolygon", "coordinates": [[[[10,2],[14,2],[14,6],[10,6]]], [[[10,10],[14,10],[14,14],[10,14]]]]}},
+    {"type": "Feature", "properties": {"name": "aisle", "kind": "lane", "cost": 3, "width": 2},
+     "geometry": {"type": "LineString", "coordinates": [[0,18],[19,18]]}}
+  ]
+}`
+
+func TestLoadZonesGeoJSON(t *testing.T) {
+	zs, err := LoadZones(strings.NewReader(zonesGeoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zs.OffLaneCost != 7 || len(zs.Zones) != 4 {
+		t.Fatalf("off lane cost %d, %d zones", zs.OffLaneCost, len(zs.Zones))
+	}
+	if z := zs.Zones[0]; z.Name != "wall" || z.Kind != ZoneKeepOut || len(z.Polygon) != 5 {
+		t.Errorf("zone 0: %+v", z)
+	}
+	if zs.Zones[1].Name != "slow" || zs.Zones[2].Name != "slow" || zs.Zones[2].Polygon[0] != [2]float64{10, 10} {
+		t.Errorf("multi polygon: %+v %+v", zs.Zones[1], zs.Zones[2])
+	}
+	if z := zs.Zones[3]; z.Kind != ZoneLane || z.Width != 2 || len(z.Polygon) != 2 {
+		t.Errorf("line zone: %+v", z)
+	}
+	if !zs.Zones[0].Contains(4, 4) || zs.Zones[0].Contains(8, 4) || !zs.Zones[3].Contains(5, 19) || zs.Zones[3].Contains(5, 20) {
+		t.Error("wrong Contains")
+	}
+}
+
+func TestZonesApply(t *testing.T) {
+	zs, err := LoadZones(strings.NewReader(zonesGeoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewWeightedAstar(nil, 20, 20, 0)
+	m := NewLayeredCostMap(a, nil, 0)
+	if err := zs.Apply(m); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		x, y int
+		want byte
+	}{
+		{4, 4, 0xff},    // keepout
+		{12, 4, 40 + 7}, // slow zone off the lane
+		{12, 12, 40 + 7},
+		{8, 8, 7},  // off lane
+		{5, 18, 0}, // lane
+		{5, 19, 0},
+	} {
+		if got := a.CostMap[c.x][c.y]; got != c.want {
+			t.Errorf("cost of (%d, %d) = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+	if a.verifyPoint(4, 4) {
+		t.Error("keepout zone is traversable")
+	}
+	// applying again replaces the zones
+	zs.Zones = zs.Zones[3:]
+	if err := zs.Apply(m); err != nil {
+		t.Fatal(err)
+	}
+	if a.CostMap[4][4] != 7 || a.CostMap[12][4] != 7 {
+		t.Errorf("costs %d %d after removing zones", a.CostMap[4][4], a.CostMap[12][4])
+	}
+}
+
+func TestLoadZonesInvalid(t *testing.T) {
+	for name, src := range map[string]string{
+		"json":     `{"type": "FeatureCollection", "features": [`,
+		"geometry": `{"type": "FeatureCollection", "features": [{"properties": {"kind": "keepout"}, "geometry": {"type": "Point", "coordinates": [1,2]}}]}`,
+		"coords":   `{"type": "FeatureCollection", "features": [{"properties": {"kind": "keepout"}, "geometry": {"type": "Polygon", "coordinates": [1,2]}}]}`,
+		"kind":     `{"type": "FeatureCollection", "features": [{"properties": {"kind": "fast"}, "geometry": {"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1]]]}}]}`,
+		"width":    `{"type": "FeatureCollection", "features": [{"properties": {"kind": "lane"}, "geometry": {"type": "LineString", "coordinates": [[0,0],[1,0]]}}]}`,
+		"polygon":  `{"zones": [{"name": "z", "kind": "slow", "polygon": [[0,0],[1,0]]}]}`,
+	} {
+		if _, err := LoadZones(strings.NewReader(src)); err == nil {
+			t.Errorf("invalid %s is loaded", name)
+		}
+	}
+}